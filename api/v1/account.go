@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type tokenInput struct {
+	Token string `json:"token"`
+}
+
+type emailInput struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (a *API) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input tokenInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("verify email, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	err = a.accounts.VerifyEmail(r.Context(), input.Token)
+	if err == service.ErrInvalidToken {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("verify email error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	var input emailInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("resend verification email, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if !a.throttleMail(w, r, input.Email, "resend verification email") {
+		return
+	}
+	err = a.accounts.ResendVerificationEmail(r.Context(), input.Email)
+	if err != nil {
+		a.log.Errorf("resend verification email error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input emailInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("forgot password, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if !a.throttleMail(w, r, input.Email, "forgot password") {
+		return
+	}
+	err = a.accounts.ForgotPassword(r.Context(), input.Email)
+	if err != nil {
+		a.log.Errorf("forgot password error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input resetPasswordInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("reset password, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	hashed, err := a.passwords.Hash(input.Password, "")
+	if service.IsPasswordPolicyError(err) {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("reset password, password hashing error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	err = a.accounts.ResetPassword(r.Context(), input.Token, hashed)
+	if err == service.ErrInvalidToken {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("reset password error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+// throttleMail counts an email requested without signing in and answers
+// with 429 once the address or client has asked for too many.
+func (a *API) throttleMail(w http.ResponseWriter, r *http.Request, email, action string) bool {
+	wait, err := a.throttle.ThrottleMail(r.Context(), email, clientIP(r))
+	if err == service.ErrTooManyEmails {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		server.ErrorJSON(w, r, http.StatusTooManyRequests, err)
+		return false
+	}
+	if err != nil {
+		a.log.Errorf("%s: throttle mail error: %s", action, err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
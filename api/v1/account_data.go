@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+const (
+	deletePostsAnonymize = "anonymize"
+	deletePostsTransfer  = "transfer"
+)
+
+var ErrInvalidDeleteMode = errors.New("posts must be either anonymize or transfer, transfer requires transfer_to")
+
+type deleteAccount struct {
+	Posts      string `json:"posts"`
+	TransferTo int    `json:"transfer_to"`
+}
+
+func (a *API) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	export, err := a.accountData.Export(r.Context(), u.ID)
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: export account: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-export-%s.zip", export.Account.Username, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", export.Account},
+		{"profile.json", export.Profile},
+		{"posts.json", export.Posts},
+		{"tags.json", export.Tags},
+		{"sessions.json", export.Sessions},
+		{"api_tokens.json", export.APITokens},
+		{"identities.json", export.Identities},
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			a.log.Errorf("error: export account: %s", err.Error())
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			a.log.Errorf("error: export account: %s", err.Error())
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		a.log.Errorf("error: export account: %s", err.Error())
+	}
+}
+
+func (a *API) RequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	err := a.accountData.RequestDeletion(r.Context(), u.ID, clientIP(r))
+	if err != nil {
+		a.log.Errorf("error: request account deletion: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSONWithCode(w, r, http.StatusAccepted, "ok")
+}
+
+func (a *API) CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	err := a.accountData.CancelDeletion(r.Context(), u.ID)
+	if err != nil {
+		a.log.Errorf("error: cancel account deletion: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) GetDeletionRequests(w http.ResponseWriter, r *http.Request) {
+	requests, err := a.accountData.GetDeletionRequests(r.Context())
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get deletion requests: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, requests)
+}
+
+func (a *API) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var input deleteAccount
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: delete user, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	del := domain.DeleteAccount{UserID: int(id)}
+	switch {
+	case input.Posts == deletePostsAnonymize && input.TransferTo == 0:
+	case input.Posts == deletePostsTransfer && input.TransferTo > 0:
+		del.TransferTo = input.TransferTo
+	default:
+		server.ErrorJSON(w, r, http.StatusBadRequest, ErrInvalidDeleteMode)
+		return
+	}
+	err = a.accountData.DeleteAccount(r.Context(), del, clientIP(r))
+	switch err {
+	case nil:
+	case service.ErrAccessDenied:
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	case service.ErrNotFound:
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	case service.ErrInvalidTransferTarget:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	default:
+		a.log.Errorf("error: delete user: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+type updateUserRole struct {
+	Role string `json:"role"`
+}
+
+func (a *API) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := a.users.GetUsers(r.Context())
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get users: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, users)
+}
+
+func (a *API) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var input updateUserRole
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: update user role, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	err = a.users.UpdateUserRole(r.Context(), domain.UpdateUserRole{
+		ID:   int(id),
+		Role: input.Role,
+	})
+	switch err {
+	case nil:
+	case service.ErrInvalidRole:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	case service.ErrNotFound:
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	case service.ErrAccessDenied:
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	default:
+		a.log.Errorf("error: update user role: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
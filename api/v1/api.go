@@ -5,57 +5,156 @@ import (
 	"github.com/scraletteykt/my-blog/internal/config"
 	mw "github.com/scraletteykt/my-blog/internal/middleware"
 	"github.com/scraletteykt/my-blog/internal/middleware/auth"
+	"github.com/scraletteykt/my-blog/internal/middleware/csrf"
 	"github.com/scraletteykt/my-blog/internal/service"
+	pkgauth "github.com/scraletteykt/my-blog/pkg/auth"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/sign"
 )
 
 type API struct {
-	cfg   *config.Config
-	users *service.UsersService
-	posts *service.PostsService
-	tags  *service.TagsService
-	log   logger.Logger
+	cfg         *config.Config
+	keys        *sign.KeyRing
+	signer      *sign.Signer
+	users       *service.UsersService
+	posts       *service.PostsService
+	tags        *service.TagsService
+	tokens      *service.TokensService
+	sessions    *service.SessionsService
+	accounts    *service.AccountsService
+	mfa         *service.MFAService
+	apiTokens   *service.APITokensService
+	throttle    *service.LoginThrottleService
+	oidc        *service.OIDCService
+	accountData *service.AccountDataService
+	passwords   *service.PasswordsService
+	redirects   *service.RedirectsService
+	log         logger.Logger
 }
 
-func NewAPI(cfg *config.Config, users *service.UsersService, posts *service.PostsService, tags *service.TagsService, log logger.Logger) *API {
+func NewAPI(cfg *config.Config, services *service.Services, keys *sign.KeyRing, log logger.Logger) *API {
 	return &API{
-		cfg:   cfg,
-		users: users,
-		posts: posts,
-		tags:  tags,
-		log:   log,
+		cfg:         cfg,
+		keys:        keys,
+		signer:      sign.NewSigner(keys),
+		users:       services.Users,
+		posts:       services.Posts,
+		tags:        services.Tags,
+		tokens:      services.Tokens,
+		sessions:    services.Sessions,
+		accounts:    services.Accounts,
+		mfa:         services.MFA,
+		apiTokens:   services.APITokens,
+		throttle:    services.LoginThrottle,
+		oidc:        services.OIDC,
+		accountData: services.AccountData,
+		passwords:   services.Passwords,
+		redirects:   services.Redirects,
+		log:         log,
 	}
 }
 
 func (a *API) Router() chi.Router {
 	r := chi.NewRouter()
 
-	mwAuth := auth.New(&auth.Config{Secret: a.cfg.Auth.Secret}, a.users)
+	mwAuth := auth.New(&auth.Config{Keys: a.keys}, a.sessions, a.tokens, a.apiTokens)
 	r.Use(mwAuth.Handler)
-	r.Use(mw.Middleware()...)
+	r.Use(csrf.New(&csrf.Config{Keys: a.keys}).Handler)
+	r.Use(mw.Middleware(a.cfg.HTTP.TrustedProxies)...)
 
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/sign-up", a.SignUp)
 			r.Post("/sign-in", a.SignIn)
+			r.Post("/sign-in/2fa", a.SignInMFA)
+			r.Post("/refresh", a.Refresh)
+			r.Post("/sign-out", a.SignOut)
+			r.With(auth.RequireUser).Get("/csrf", a.GetCSRFToken)
+			r.Post("/verify-email", a.VerifyEmail)
+			r.Post("/verify-email/resend", a.ResendVerificationEmail)
+			r.Post("/forgot-password", a.ForgotPassword)
+			r.Post("/reset-password", a.ResetPassword)
+			r.Route("/oidc", func(r chi.Router) {
+				r.Get("/login", a.OIDCLogin)
+				r.With(auth.RequireUser, auth.RequireUnscoped).Get("/link", a.OIDCLink)
+				r.Get("/callback", a.OIDCCallback)
+			})
+			r.Route("/2fa", func(r chi.Router) {
+				r.Use(auth.RequireUser, auth.RequireUnscoped)
+				r.Post("/enroll", a.EnrollMFA)
+				r.Post("/confirm", a.ConfirmMFA)
+				r.Post("/recovery-codes", a.RegenerateRecoveryCodes)
+				r.Post("/disable", a.DisableMFA)
+			})
+			r.Route("/sessions", func(r chi.Router) {
+				r.Use(auth.RequireUser, auth.RequireUnscoped)
+				r.Get("/", a.GetSessions)
+				r.Delete("/", a.RevokeOtherSessions)
+				r.Delete("/{sessionID}", a.RevokeSession)
+			})
+			r.Route("/tokens", func(r chi.Router) {
+				r.Use(auth.RequireUser, auth.RequireUnscoped)
+				r.Get("/", a.GetAPITokens)
+				r.Post("/", a.CreateAPIToken)
+				r.Delete("/{tokenID}", a.RevokeAPIToken)
+			})
+		})
+		r.Route("/me", func(r chi.Router) {
+			r.Use(auth.RequireUser)
+			r.Get("/profile", a.GetMyProfile)
+			r.Get("/posts", a.GetMyPosts)
+			r.Get("/posts/scheduled", a.GetMyScheduledPosts)
+			r.With(auth.RequireUnscoped).Put("/profile", a.UpdateMyProfile)
+			r.With(auth.RequireUnscoped).Get("/export", a.ExportAccount)
+			r.With(auth.RequireUnscoped).Post("/deletion", a.RequestAccountDeletion)
+			r.With(auth.RequireUnscoped).Delete("/deletion", a.CancelAccountDeletion)
+		})
+		r.Route("/users/{username}", func(r chi.Router) {
+			r.Get("/", a.GetProfile)
+			r.Get("/posts", a.GetPostsByUser)
 		})
 		r.Route("/posts", func(r chi.Router) {
 			r.Get("/", a.GetPosts)
-			r.Post("/", a.CreatePost)
+			r.With(auth.Require(pkgauth.PermissionPostsWrite)).Post("/", a.CreatePost)
+			r.Get("/by-slug/{slug}", a.GetPostBySlug)
+			r.Get("/search", a.SearchPosts)
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Get("/", a.GetPostByID)
-				r.Put("/", a.UpdatePost)
-				r.Delete("/", a.DeletePost)
+				r.With(auth.RequireUser).Put("/", a.UpdatePost)
+				r.With(auth.RequireUser).Delete("/", a.DeletePost)
+				r.With(auth.RequireUser).Put("/schedule", a.SchedulePost)
+				r.With(auth.RequireUser).Delete("/schedule", a.CancelSchedule)
+				r.Route("/revisions", func(r chi.Router) {
+					r.Use(auth.RequireUser)
+					r.Get("/", a.GetPostRevisions)
+					r.Get("/diff", a.DiffPostRevisions)
+					r.Get("/{revisionID}", a.GetPostRevision)
+					r.Post("/{revisionID}/restore", a.RestorePostRevision)
+				})
 			})
 		})
 		r.Route("/tags", func(r chi.Router) {
 			r.Get("/", a.GetTags)
-			r.Post("/", a.CreateTag)
+			r.With(auth.Require(pkgauth.PermissionTagsWrite)).Post("/", a.CreateTag)
+			r.Get("/by-slug/{slug}", a.GetTagBySlug)
 			r.Route("/{tagID}", func(r chi.Router) {
 				r.Get("/", a.GetTagByID)
 				r.Get("/posts", a.GetPostsByTag)
-				r.Put("/", a.UpdateTag)
-				r.Delete("/", a.DeleteTag)
+				r.With(auth.Require(pkgauth.PermissionTagsWrite)).Put("/", a.UpdateTag)
+				r.With(auth.Require(pkgauth.PermissionTagsWrite)).Delete("/", a.DeleteTag)
+			})
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.Require(pkgauth.PermissionUsersManage))
+			r.Route("/users", func(r chi.Router) {
+				r.Get("/", a.GetUsers)
+				r.Put("/{userID}/role", a.UpdateUserRole)
+				r.Delete("/{userID}", a.DeleteUser)
+			})
+			r.Get("/deletion-requests", a.GetDeletionRequests)
+			r.Route("/redirects", func(r chi.Router) {
+				r.Get("/", a.GetRedirects)
+				r.Delete("/{redirectID}", a.DeleteRedirect)
 			})
 		})
 	})
@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type createAPIToken struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (a *API) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	tokens, err := a.apiTokens.GetTokens(r.Context(), u.ID)
+	if err != nil {
+		a.log.Errorf("error: get api tokens: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, tokens)
+}
+
+func (a *API) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	var input createAPIToken
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: create api token, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	created, err := a.apiTokens.CreateToken(r.Context(), domain.CreateAPIToken{
+		UserID:    u.ID,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	switch err {
+	case nil:
+	case service.ErrInvalidScope, service.ErrInvalidTokenName, service.ErrInvalidExpiration:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	case service.ErrAccessDenied:
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	default:
+		a.log.Errorf("error: create api token: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSONWithCode(w, r, http.StatusCreated, created)
+}
+
+func (a *API) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "tokenID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	err = a.apiTokens.RevokeToken(r.Context(), u.ID, int(id))
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: revoke api token: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
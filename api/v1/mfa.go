@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+)
+
+type mfaCodeInput struct {
+	Code string `json:"code"`
+}
+
+type signInMFAInput struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type disableMFAInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type recoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (a *API) SignInMFA(w http.ResponseWriter, r *http.Request) {
+	var input signInMFAInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("user sign in 2fa, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u, err := a.mfa.CompleteChallenge(r.Context(), input.MFAToken, input.Code)
+	if err == service.ErrInvalidToken || err == service.ErrInvalidMFACode || err == service.ErrMFANotEnabled {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("user sign in 2fa error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	tokens, err := a.startSession(w, r, u)
+	if err != nil {
+		a.log.Errorf("user sign in 2fa: start session error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSONWithCode(w, r, http.StatusOK, tokens)
+}
+
+func (a *API) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	enrollment, err := a.mfa.Enroll(r.Context(), u.ID)
+	if err == service.ErrMFAAlreadyEnabled {
+		server.ErrorJSON(w, r, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: enroll 2fa: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, enrollment)
+}
+
+func (a *API) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	var input mfaCodeInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: confirm 2fa, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	codes, err := a.mfa.Confirm(r.Context(), u.ID, input.Code)
+	a.respondRecoveryCodes(w, r, codes, err)
+}
+
+func (a *API) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var input mfaCodeInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: regenerate recovery codes, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	codes, err := a.mfa.RegenerateRecoveryCodes(r.Context(), u.ID, input.Code)
+	a.respondRecoveryCodes(w, r, codes, err)
+}
+
+func (a *API) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var input disableMFAInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: disable 2fa, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	user, err := a.users.GetUser(r.Context(), u.Username)
+	if err != nil {
+		a.log.Errorf("error: disable 2fa, get user: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if !a.passwords.Verify(r.Context(), user, input.Password) {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New("wrong password"))
+		return
+	}
+	err = a.mfa.Disable(r.Context(), u.ID, input.Code)
+	switch err {
+	case nil:
+	case service.ErrInvalidMFACode:
+		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+		return
+	case service.ErrMFANotEnabled:
+		server.ErrorJSON(w, r, http.StatusConflict, err)
+		return
+	default:
+		a.log.Errorf("error: disable 2fa: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) respondRecoveryCodes(w http.ResponseWriter, r *http.Request, codes []string, err error) {
+	switch err {
+	case nil:
+	case service.ErrInvalidMFACode:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	case service.ErrMFAAlreadyEnabled, service.ErrMFANotEnabled, service.ErrMFANotEnrolled:
+		server.ErrorJSON(w, r, http.StatusConflict, err)
+		return
+	default:
+		a.log.Errorf("error: recovery codes: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, recoveryCodes{RecoveryCodes: codes})
+}
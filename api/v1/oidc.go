@@ -0,0 +1,164 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/cookie"
+	"github.com/scraletteykt/my-blog/pkg/oidc"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var ErrInvalidOIDCState = errors.New("invalid or expired sign in state")
+
+// oidcState is kept in a signed cookie between the redirect to the identity
+// provider and the callback.
+type oidcState struct {
+	State      string `json:"state"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID int    `json:"link_user_id,omitempty"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+func (a *API) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	a.startOIDC(w, r, 0)
+}
+
+func (a *API) OIDCLink(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	a.startOIDC(w, r, u.ID)
+}
+
+func (a *API) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	state, err := a.readOIDCState(r)
+	http.SetCookie(w, cookie.NewExpiredOIDCStateCookie(a.cfg.HTTP.SecureCookies))
+	if err != nil || r.URL.Query().Get("state") != state.State {
+		server.ErrorJSON(w, r, http.StatusBadRequest, ErrInvalidOIDCState)
+		return
+	}
+	if e := r.URL.Query().Get("error"); e != "" {
+		a.log.Warnf("oidc callback, provider error: %s: %s", e, r.URL.Query().Get("error_description"))
+		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New(e))
+		return
+	}
+	code := r.URL.Query().Get("code")
+
+	if state.LinkUserID > 0 {
+		u := auth.FromContext(r.Context())
+		if u.ID != state.LinkUserID {
+			server.ErrorJSON(w, r, http.StatusForbidden, ErrInvalidOIDCState)
+			return
+		}
+		err = a.oidc.Link(r.Context(), u.ID, code, state.Verifier, state.Nonce)
+		if err == service.ErrIdentityLinked {
+			server.ErrorJSON(w, r, http.StatusConflict, err)
+			return
+		}
+		if err == service.ErrInvalidToken {
+			server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+			return
+		}
+		if err != nil {
+			a.log.Errorf("error: oidc link: %s", err.Error())
+			server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		server.ResponseJSON(w, r, "ok")
+		return
+	}
+
+	u, err := a.oidc.Login(r.Context(), code, state.Verifier, state.Nonce)
+	if err == service.ErrIdentityNotLinked || err == service.ErrInvalidToken {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: oidc login: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	tokens, err := a.startSession(w, r, u)
+	if err != nil {
+		a.log.Errorf("error: oidc login: start session: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if redirect := a.oidc.PostLoginURL(); redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	server.ResponseJSONWithCode(w, r, http.StatusOK, tokens)
+}
+
+func (a *API) startOIDC(w http.ResponseWriter, r *http.Request, linkUserID int) {
+	state := oidcState{
+		LinkUserID: linkUserID,
+		ExpiresAt:  time.Now().Add(oidcStateTTL).Unix(),
+	}
+	var err error
+	for _, v := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		if *v, err = oidc.NewRandom(); err != nil {
+			a.log.Errorf("error: oidc start: %s", err.Error())
+			server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	url, err := a.oidc.AuthCodeURL(r.Context(), state.State, state.Nonce, state.Verifier)
+	if err == service.ErrOIDCDisabled {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: oidc start: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	value := base64.RawURLEncoding.EncodeToString(payload)
+	value += cookie.IDCookieSep + a.signer.Sign(value)
+	http.SetCookie(w, cookie.NewOIDCStateCookie(value, int(oidcStateTTL.Seconds()), a.cfg.HTTP.SecureCookies))
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (a *API) readOIDCState(r *http.Request) (*oidcState, error) {
+	c, err := r.Cookie(cookie.OIDCStateCookieName)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(c.Value, cookie.IDCookieSep)
+	if len(parts) != 2 {
+		return nil, ErrInvalidOIDCState
+	}
+	if !a.signer.Verify(parts[1], parts[0]) {
+		return nil, ErrInvalidOIDCState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	var state oidcState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	if time.Now().Unix() > state.ExpiresAt {
+		return nil, ErrInvalidOIDCState
+	}
+	return &state, nil
+}
@@ -13,16 +13,24 @@ import (
 )
 
 const (
-	pageQueryKey = "page"
-	postsOnPage  = 30
+	pageQueryKey   = "page"
+	limitQueryKey  = "limit"
+	cursorQueryKey = "cursor"
+	sortQueryKey   = "sort"
+	orderQueryKey  = "order"
+	countQueryKey  = "count"
+	postsOnPage    = 30
+	maxPostsOnPage = 100
 )
 
 type createPost struct {
 	ReadingTime int    `json:"reading_time"`
 	Title       string `json:"title"`
 	Subtitle    string `json:"subtitle"`
+	Excerpt     string `json:"excerpt"`
 	ImageURL    string `json:"image_url"`
 	Content     string `json:"content"`
+	Format      string `json:"format"`
 	Slug        string `json:"slug"`
 	TagIDs      []int  `json:"tags"`
 }
@@ -33,8 +41,10 @@ type updatePost struct {
 	Publish     *bool   `json:"publish"`
 	Title       *string `json:"title"`
 	Subtitle    *string `json:"subtitle"`
+	Excerpt     *string `json:"excerpt"`
 	ImageURL    *string `json:"image_url"`
 	Content     *string `json:"content"`
+	Format      *string `json:"format"`
 	Slug        *string `json:"slug"`
 	TagIDs      *[]int  `json:"tags"`
 }
@@ -55,10 +65,38 @@ func (a *API) GetPostByID(w http.ResponseWriter, r *http.Request) {
 		a.log.Errorf("error: get post by id: %s", err.Error())
 		return
 	}
+	a.posts.CountPostView(r.Context(), p)
+	server.ResponseJSON(w, r, p)
+}
+
+func (a *API) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	p, err := a.posts.GetPostBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if movedPermanently(w, r, "/api/posts/by-slug/", err) {
+		return
+	}
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get post by slug: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	a.posts.CountPostView(r.Context(), p)
 	server.ResponseJSON(w, r, p)
 }
 
 func (a *API) GetPosts(w http.ResponseWriter, r *http.Request) {
+	cursor, err := cursorPaging(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if cursor {
+		a.listPosts(w, r, 0, "get posts")
+		return
+	}
 	var page int64
 	if p := r.URL.Query().Get(pageQueryKey); p != "" {
 		page, _ = strconv.ParseInt(p, 10, 0)
@@ -70,7 +108,19 @@ func (a *API) GetPosts(w http.ResponseWriter, r *http.Request) {
 	if page == 0 {
 		page = 1
 	}
-	posts, err := a.posts.GetPosts(r.Context(), postsOnPage, uint64((page-1)*postsOnPage))
+	sort, err := postSortParam(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	posts, err := a.posts.GetPosts(r.Context(), postFilterParams(r), sort, postsOnPage, uint64((page-1)*postsOnPage))
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if err == service.ErrInvalidSort {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
 	if err == service.ErrNotFound {
 		server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
 		return
@@ -84,6 +134,20 @@ func (a *API) GetPosts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *API) GetPostsByTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.ParseInt(chi.URLParam(r, "tagID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	cursor, err := cursorPaging(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if cursor {
+		a.listPosts(w, r, int(tagID), "get posts by tag")
+		return
+	}
 	var page int64
 	if p := r.URL.Query().Get(pageQueryKey); p != "" {
 		page, _ = strconv.ParseInt(p, 10, 0)
@@ -95,12 +159,19 @@ func (a *API) GetPostsByTag(w http.ResponseWriter, r *http.Request) {
 	if page == 0 {
 		page = 1
 	}
-	tagID, err := strconv.ParseInt(chi.URLParam(r, "tagID"), 10, 0)
+	sort, err := postSortParam(r)
 	if err != nil {
 		server.ErrorJSON(w, r, http.StatusBadRequest, err)
 		return
 	}
-	posts, err := a.posts.GetPostsByTag(r.Context(), int(tagID), postsOnPage, uint64((page-1)*postsOnPage))
+	posts, err := a.posts.GetPostsByTag(r.Context(), int(tagID), postFilterParams(r), sort, postsOnPage, uint64((page-1)*postsOnPage))
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if err == service.ErrInvalidSort {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
 	if err == service.ErrNotFound {
 		server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
 		return
@@ -131,11 +202,20 @@ func (a *API) CreatePost(w http.ResponseWriter, r *http.Request) {
 		ReadingTime: cpost.ReadingTime,
 		Title:       cpost.Title,
 		Subtitle:    cpost.Subtitle,
+		Excerpt:     cpost.Excerpt,
 		ImageURL:    cpost.ImageURL,
 		Content:     cpost.Content,
+		Format:      cpost.Format,
 		Slug:        cpost.Slug,
 		TagIDs:      cpost.TagIDs,
 	})
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if validationErrorJSON(w, r, err) {
+		return
+	}
 	if err != nil {
 		a.log.Errorf("error: post create: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
@@ -151,7 +231,6 @@ func (a *API) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		server.ErrorJSON(w, r, http.StatusBadRequest, err)
 		return
 	}
-	u := auth.FromContext(r.Context())
 	err = json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
 		a.log.Warnf("warn: post update: decoder error: %s", err.Error())
@@ -169,16 +248,17 @@ func (a *API) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		server.ErrorJSON(w, r, http.StatusBadRequest, err)
 		return
 	}
-	if u.ID != originalPost.UserID {
-		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
-		return
-	}
 	updPost := domain.UpdatePost{ID: input.ID}
 	if input.ReadingTime != nil {
 		updPost.ReadingTime = *input.ReadingTime
-	} else {
+	} else if originalPost.ManualReadingTime {
 		updPost.ReadingTime = originalPost.ReadingTime
 	}
+	if input.Excerpt != nil {
+		updPost.Excerpt = *input.Excerpt
+	} else if originalPost.ManualExcerpt {
+		updPost.Excerpt = originalPost.Excerpt
+	}
 	if input.Title != nil {
 		updPost.Title = *input.Title
 	} else {
@@ -199,6 +279,11 @@ func (a *API) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	} else {
 		updPost.Content = originalPost.Content
 	}
+	if input.Format != nil {
+		updPost.Format = *input.Format
+	} else {
+		updPost.Format = originalPost.Format
+	}
 	if input.Slug != nil {
 		updPost.Slug = *input.Slug
 	} else {
@@ -220,6 +305,13 @@ func (a *API) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = a.posts.UpdatePost(r.Context(), updPost)
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if validationErrorJSON(w, r, err) {
+		return
+	}
 	if err != nil {
 		a.log.Errorf("error: post update: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
@@ -234,8 +326,7 @@ func (a *API) DeletePost(w http.ResponseWriter, r *http.Request) {
 		server.ErrorJSON(w, r, http.StatusBadRequest, err)
 		return
 	}
-	u := auth.FromContext(r.Context())
-	originalPost, err := a.posts.GetPostByID(r.Context(), int(id))
+	_, err = a.posts.GetPostByID(r.Context(), int(id))
 	if err == service.ErrNotFound {
 		server.ErrorJSON(w, r, http.StatusNotFound, err)
 		return
@@ -245,11 +336,11 @@ func (a *API) DeletePost(w http.ResponseWriter, r *http.Request) {
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	if u.ID != originalPost.UserID {
-		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+	err = a.posts.DeletePost(r.Context(), domain.DeletePost{ID: int(id)})
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
 		return
 	}
-	err = a.posts.DeletePost(r.Context(), domain.DeletePost{ID: int(id)})
 	if err != nil {
 		a.log.Errorf("error: post delete: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// cursorPaging reports whether a listing asks for cursor pages, which come
+// wrapped in a PostPage. Other requests keep getting the plain list paged
+// with the page param, so the two can't be mixed.
+func cursorPaging(r *http.Request) (bool, error) {
+	query := r.URL.Query()
+	cursor := query.Get(cursorQueryKey) != "" || query.Get(limitQueryKey) != "" || query.Get(countQueryKey) != ""
+	if cursor && query.Get(pageQueryKey) != "" {
+		return false, errors.New("page param can't be combined with cursor, limit or count")
+	}
+	return cursor, nil
+}
+
+// listPosts answers with a cursor page of published posts.
+func (a *API) listPosts(w http.ResponseWriter, r *http.Request, tagID int, action string) {
+	listing, err := postListingParams(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	listing.TagID = tagID
+	page, err := a.posts.ListPosts(r.Context(), listing)
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if err == service.ErrInvalidCursor || err == service.ErrInvalidSort {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: %s: %s", action, err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, page)
+}
+
+func (a *API) GetMyPosts(w http.ResponseWriter, r *http.Request) {
+	listing, err := postListingParams(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	dashboard, err := a.posts.GetMyPosts(r.Context(), listing)
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if err == service.ErrInvalidCursor || err == service.ErrInvalidSort {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get my posts: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, dashboard)
+}
+
+// postListingParams reads the filter, sort and paging params of a listing.
+func postListingParams(r *http.Request) (domain.PostListing, error) {
+	query := r.URL.Query()
+	sort, err := postSortParam(r)
+	if err != nil {
+		return domain.PostListing{}, err
+	}
+	listing := domain.PostListing{
+		Filter: postFilterParams(r),
+		Sort:   sort,
+		Cursor: query.Get(cursorQueryKey),
+		Limit:  postsOnPage,
+	}
+	if l := query.Get(limitQueryKey); l != "" {
+		limit, err := strconv.ParseUint(l, 10, 0)
+		if err != nil || limit == 0 {
+			return listing, errors.New("limit param must be positive")
+		}
+		if limit > maxPostsOnPage {
+			limit = maxPostsOnPage
+		}
+		listing.Limit = limit
+	}
+	if c := query.Get(countQueryKey); c != "" {
+		withTotal, err := strconv.ParseBool(c)
+		if err != nil {
+			return listing, errors.New("count param must be a boolean")
+		}
+		listing.WithTotal = withTotal
+	}
+	return listing, nil
+}
+
+// postFilterParams reads the filter params, tags being a comma separated
+// list of slugs. The service reports the ones that are invalid.
+func postFilterParams(r *http.Request) domain.PostFilter {
+	query := r.URL.Query()
+	filter := domain.PostFilter{
+		TagsMatch: query.Get("tags_match"),
+		Author:    query.Get("author"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Status:    query.Get("status"),
+		HasImage:  query.Get("has_image"),
+	}
+	for _, tag := range strings.Split(query.Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	return filter
+}
+
+// postSortParam reads the sort and order params. Titles are listed A to Z
+// and everything else largest first unless the order says otherwise.
+func postSortParam(r *http.Request) (domain.PostSort, error) {
+	query := r.URL.Query()
+	sort := domain.PostSort{Field: query.Get(sortQueryKey)}
+	switch query.Get(orderQueryKey) {
+	case "":
+		sort.Ascending = sort.Field == domain.PostSortTitle
+	case "asc":
+		sort.Ascending = true
+	case "desc":
+	default:
+		return sort, errors.New("order param must be asc or desc")
+	}
+	return sort, nil
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/pkg/server"
+)
+
+func TestPostSortParam(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    domain.PostSort
+		wantErr bool
+	}{
+		{"", domain.PostSort{}, false},
+		{"?sort=published", domain.PostSort{Field: domain.PostSortPublished}, false},
+		{"?sort=published&order=asc", domain.PostSort{Field: domain.PostSortPublished, Ascending: true}, false},
+		{"?sort=updated&order=desc", domain.PostSort{Field: domain.PostSortUpdated}, false},
+		{"?sort=title", domain.PostSort{Field: domain.PostSortTitle, Ascending: true}, false},
+		{"?sort=title&order=desc", domain.PostSort{Field: domain.PostSortTitle}, false},
+		{"?sort=popular", domain.PostSort{Field: domain.PostSortPopular}, false},
+		{"?sort=popular&order=asc", domain.PostSort{Field: domain.PostSortPopular, Ascending: true}, false},
+		{"?sort=popular&order=up", domain.PostSort{}, true},
+	}
+	for _, tt := range tests {
+		sort, err := postSortParam(httptest.NewRequest("GET", "/posts"+tt.query, nil))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v", tt.query, err)
+			continue
+		}
+		if err == nil && sort != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.query, sort, tt.want)
+		}
+	}
+}
+
+func TestCursorPaging(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"?page=2", false, false},
+		{"?sort=title", false, false},
+		{"?limit=5", true, false},
+		{"?cursor=abc", true, false},
+		{"?count=true", true, false},
+		{"?page=2&limit=5", false, true},
+	}
+	for _, tt := range tests {
+		cursor, err := cursorPaging(httptest.NewRequest("GET", "/posts"+tt.query, nil))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v", tt.query, err)
+			continue
+		}
+		if cursor != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.query, cursor, tt.want)
+		}
+	}
+}
+
+func TestPostPageJSONKeepsOrder(t *testing.T) {
+	ids := []int{50, 7, 31, 12, 99, 1}
+	page := &domain.PostPage{Next: "next"}
+	for _, id := range ids {
+		page.Items = append(page.Items, &domain.Post{ID: id})
+	}
+
+	w := httptest.NewRecorder()
+	server.ResponseJSON(w, httptest.NewRequest("GET", "/posts?limit=6", nil), page)
+
+	var got struct {
+		Items []struct {
+			ID int `json:"id"`
+		} `json:"items"`
+		Next string `json:"next"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != len(ids) {
+		t.Fatalf("got %d posts, want %d", len(got.Items), len(ids))
+	}
+	for i, item := range got.Items {
+		if item.ID != ids[i] {
+			t.Errorf("post %d: got id %d, want %d", i, item.ID, ids[i])
+		}
+	}
+	if got.Next != page.Next {
+		t.Errorf("got next %q, want %q", got.Next, page.Next)
+	}
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+func (a *API) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	revisions, err := a.posts.GetRevisions(r.Context(), int(postID))
+	if a.revisionError(w, r, "get post revisions", err) {
+		return
+	}
+	server.ResponseJSON(w, r, revisions)
+}
+
+func (a *API) GetPostRevision(w http.ResponseWriter, r *http.Request) {
+	postID, revisionID, err := revisionParams(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	revision, err := a.posts.GetRevision(r.Context(), postID, revisionID)
+	if a.revisionError(w, r, "get post revision", err) {
+		return
+	}
+	server.ResponseJSON(w, r, revision)
+}
+
+func (a *API) DiffPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		server.ErrorJSON(w, r, http.StatusBadRequest, errors.New("from is required"))
+		return
+	}
+	if to == "" {
+		to = service.RevisionCurrent
+	}
+	d, err := a.posts.DiffRevisions(r.Context(), int(postID), from, to)
+	if a.revisionError(w, r, "diff post revisions", err) {
+		return
+	}
+	server.ResponseJSON(w, r, d)
+}
+
+func (a *API) RestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	postID, revisionID, err := revisionParams(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	err = a.posts.RestoreRevision(r.Context(), postID, revisionID)
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if a.revisionError(w, r, "restore post revision", err) {
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func revisionParams(r *http.Request) (int, int, error) {
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	revisionID, err := strconv.ParseInt(chi.URLParam(r, "revisionID"), 10, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(postID), int(revisionID), nil
+}
+
+// revisionError writes the response for a failed revision call and reports
+// whether there was an error.
+func (a *API) revisionError(w http.ResponseWriter, r *http.Request, action string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case err == service.ErrNotFound:
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+	case err == service.ErrAccessDenied:
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+	case err == service.ErrInvalidRevision:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+	default:
+		a.log.Errorf("error: %s: %s", action, err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type schedulePost struct {
+	PublishAt time.Time `json:"publish_at"`
+}
+
+func (a *API) SchedulePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var input schedulePost
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: schedule post, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if input.PublishAt.IsZero() {
+		server.ErrorJSON(w, r, http.StatusBadRequest, errors.New("publish_at is required"))
+		return
+	}
+	err = a.posts.SchedulePost(r.Context(), int(id), input.PublishAt)
+	if a.scheduleError(w, r, "schedule post", err) {
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	err = a.posts.CancelSchedule(r.Context(), int(id))
+	if a.scheduleError(w, r, "cancel post schedule", err) {
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) GetMyScheduledPosts(w http.ResponseWriter, r *http.Request) {
+	posts, err := a.posts.GetScheduledPosts(r.Context())
+	if err == service.ErrNotFound {
+		server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get scheduled posts: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, posts)
+}
+
+// scheduleError writes the response for a failed scheduling call and
+// reports whether there was an error.
+func (a *API) scheduleError(w http.ResponseWriter, r *http.Request, action string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case err == service.ErrNotFound:
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+	case err == service.ErrAccessDenied:
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+	case err == service.ErrPublishAtInPast:
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+	case err == service.ErrNotSchedulable, err == service.ErrNotScheduled:
+		server.ErrorJSON(w, r, http.StatusConflict, err)
+	default:
+		a.log.Errorf("error: %s: %s", action, err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+	}
+	return true
+}
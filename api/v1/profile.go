@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+type updateProfile struct {
+	DisplayName *string            `json:"display_name"`
+	Bio         *string            `json:"bio"`
+	AvatarURL   *string            `json:"avatar_url"`
+	Website     *string            `json:"website"`
+	SocialLinks *map[string]string `json:"social_links"`
+}
+
+func (a *API) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	profile, err := a.users.GetProfileByID(r.Context(), u.ID)
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get my profile: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, profile)
+}
+
+func (a *API) UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
+	var input updateProfile
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		a.log.Warnf("warn: update profile, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	original, err := a.users.GetProfileByID(r.Context(), u.ID)
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: update profile, cannot get profile: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	upd := domain.UpdateProfile{
+		UserID:      u.ID,
+		DisplayName: original.DisplayName,
+		Bio:         original.Bio,
+		AvatarURL:   original.AvatarURL,
+		Website:     original.Website,
+		SocialLinks: original.SocialLinks,
+	}
+	if input.DisplayName != nil {
+		upd.DisplayName = *input.DisplayName
+	}
+	if input.Bio != nil {
+		upd.Bio = *input.Bio
+	}
+	if input.AvatarURL != nil {
+		upd.AvatarURL = *input.AvatarURL
+	}
+	if input.Website != nil {
+		upd.Website = *input.Website
+	}
+	if input.SocialLinks != nil {
+		upd.SocialLinks = *input.SocialLinks
+	}
+	err = a.users.UpdateProfile(r.Context(), upd)
+	if validationErrorJSON(w, r, err) {
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: update profile: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) GetProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := a.users.GetProfile(r.Context(), chi.URLParam(r, "username"))
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get profile: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, profile)
+}
+
+func (a *API) GetPostsByUser(w http.ResponseWriter, r *http.Request) {
+	var page int64
+	if p := r.URL.Query().Get(pageQueryKey); p != "" {
+		page, _ = strconv.ParseInt(p, 10, 0)
+	}
+	if page < 0 {
+		server.ErrorJSON(w, r, http.StatusBadRequest, errors.New("page param must be positive"))
+		return
+	}
+	if page == 0 {
+		page = 1
+	}
+	profile, err := a.users.GetProfile(r.Context(), chi.URLParam(r, "username"))
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get posts by user: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	sort, err := postSortParam(r)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	posts, err := a.posts.GetPostsByUser(r.Context(), profile.UserID, sort, postsOnPage, uint64((page-1)*postsOnPage))
+	if err == service.ErrInvalidSort {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err == service.ErrNotFound {
+		server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get posts by user: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, posts)
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type movedResponse struct {
+	Location string `json:"location"`
+}
+
+// movedPermanently answers a lookup by a previous slug with a 301 to the
+// current one, and reports whether err was such a lookup.
+func movedPermanently(w http.ResponseWriter, r *http.Request, prefix string, err error) bool {
+	var moved *service.SlugMovedError
+	if !errors.As(err, &moved) {
+		return false
+	}
+	location := prefix + url.PathEscape(moved.Slug)
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	server.ResponseJSONWithCode(w, r, http.StatusMovedPermanently, movedResponse{Location: location})
+	return true
+}
+
+func (a *API) GetRedirects(w http.ResponseWriter, r *http.Request) {
+	redirects, err := a.redirects.GetRedirects(r.Context(), r.URL.Query().Get("kind"))
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err == service.ErrInvalidRedirectKind {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get redirects: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, redirects)
+}
+
+func (a *API) DeleteRedirect(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "redirectID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	err = a.redirects.DeleteRedirect(r.Context(), int(id))
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: delete redirect: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
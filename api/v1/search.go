@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+func (a *API) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var page int64
+	if p := query.Get(pageQueryKey); p != "" {
+		page, _ = strconv.ParseInt(p, 10, 0)
+	}
+	if page < 0 {
+		server.ErrorJSON(w, r, http.StatusBadRequest, errors.New("page param must be positive"))
+		return
+	}
+	if page == 0 {
+		page = 1
+	}
+	postSearch := domain.PostSearch{
+		Query:   query.Get("q"),
+		TagSlug: query.Get("tag"),
+		Limit:   postsOnPage,
+		Offset:  uint64((page - 1) * postsOnPage),
+	}
+	if username := query.Get("author"); username != "" {
+		profile, err := a.users.GetProfile(r.Context(), username)
+		if err == service.ErrNotFound {
+			server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
+			return
+		}
+		if err != nil {
+			a.log.Errorf("error: search posts: %s", err.Error())
+			server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		postSearch.AuthorID = profile.UserID
+	}
+	results, err := a.posts.SearchPosts(r.Context(), postSearch)
+	if err == service.ErrEmptyQuery {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err == service.ErrNotFound {
+		server.ResponseJSONWithCode(w, r, http.StatusNoContent, struct{}{})
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: search posts: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, results)
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/scraletteykt/my-blog/internal/middleware/csrf"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/cookie"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+	"strconv"
+)
+
+func (a *API) SignOut(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	if u.ID < 0 {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New("unauthorized access"))
+		return
+	}
+	err := a.sessions.RevokeSession(r.Context(), u.ID, u.SessionID)
+	if err != nil && err != service.ErrNotFound {
+		a.log.Errorf("error: sign out: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	http.SetCookie(w, cookie.NewExpiredIDCookie(a.cfg.HTTP.SecureCookies))
+	http.SetCookie(w, cookie.NewExpiredCSRFCookie(a.cfg.HTTP.SecureCookies))
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) GetSessions(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	sessions, err := a.sessions.GetSessions(r.Context(), u.ID, u.SessionID)
+	if err != nil {
+		a.log.Errorf("error: get sessions: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, sessions)
+}
+
+func (a *API) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "sessionID"), 10, 0)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	u := auth.FromContext(r.Context())
+	err = a.sessions.RevokeSession(r.Context(), u.ID, int(id))
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: revoke session: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+func (a *API) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	err := a.sessions.RevokeOtherSessions(r.Context(), u.ID, u.SessionID)
+	if err != nil {
+		a.log.Errorf("error: revoke other sessions: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, "ok")
+}
+
+type csrfToken struct {
+	Token  string `json:"csrf_token"`
+	Header string `json:"header"`
+}
+
+// GetCSRFToken hands browser clients the token they must send with
+// mutating requests authenticated by the session cookie.
+func (a *API) GetCSRFToken(w http.ResponseWriter, r *http.Request) {
+	u := auth.FromContext(r.Context())
+	if u.Source != auth.SourceCookie {
+		server.ErrorJSON(w, r, http.StatusBadRequest, errors.New("csrf token is only needed for cookie sessions"))
+		return
+	}
+	httpCookie, err := r.Cookie(cookie.IDCookieName)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New("unauthorized access"))
+		return
+	}
+	idCookie, err := cookie.ParseFromCookie(httpCookie)
+	if err != nil {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New("unauthorized access"))
+		return
+	}
+	token := csrf.Token(a.signer, idCookie.SessionToken)
+	http.SetCookie(w, cookie.NewCSRFCookie(token, int(a.sessions.TTL().Seconds()), a.cfg.HTTP.SecureCookies))
+	server.ResponseJSON(w, r, csrfToken{Token: token, Header: csrf.HeaderName})
+}
@@ -40,6 +40,23 @@ func (a *API) GetTagByID(w http.ResponseWriter, r *http.Request) {
 	server.ResponseJSON(w, r, t)
 }
 
+func (a *API) GetTagBySlug(w http.ResponseWriter, r *http.Request) {
+	t, err := a.tags.GetTagBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if movedPermanently(w, r, "/api/tags/by-slug/", err) {
+		return
+	}
+	if err == service.ErrNotFound {
+		server.ErrorJSON(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("error: get tag by slug: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	server.ResponseJSON(w, r, t)
+}
+
 func (a *API) GetTags(w http.ResponseWriter, r *http.Request) {
 	t, err := a.tags.GetTags(r.Context())
 	if err == service.ErrNotFound {
@@ -66,6 +83,13 @@ func (a *API) CreateTag(w http.ResponseWriter, r *http.Request) {
 		Name: ctag.Name,
 		Slug: ctag.Slug,
 	})
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if validationErrorJSON(w, r, err) {
+		return
+	}
 	if err != nil {
 		a.log.Errorf("error: create tag: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
@@ -110,6 +134,13 @@ func (a *API) UpdateTag(w http.ResponseWriter, r *http.Request) {
 		updTag.Slug = originalTag.Slug
 	}
 	err = a.tags.UpdateTag(r.Context(), updTag)
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
+	if validationErrorJSON(w, r, err) {
+		return
+	}
 	if err != nil {
 		a.log.Errorf("error: update tag: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
@@ -135,6 +166,10 @@ func (a *API) DeleteTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	err = a.tags.DeleteTag(r.Context(), domain.DeleteTag{ID: int(id)})
+	if err == service.ErrAccessDenied {
+		server.ErrorJSON(w, r, http.StatusForbidden, err)
+		return
+	}
 	if err != nil {
 		a.log.Errorf("error: delete tag: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
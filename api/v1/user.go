@@ -2,18 +2,20 @@ package v1
 
 import (
 	"encoding/json"
-	"errors"
 	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/middleware/csrf"
 	"github.com/scraletteykt/my-blog/internal/service"
-	"github.com/scraletteykt/my-blog/pkg/bcrypt"
 	"github.com/scraletteykt/my-blog/pkg/cookie"
 	"github.com/scraletteykt/my-blog/pkg/server"
-	"github.com/scraletteykt/my-blog/pkg/sign"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 type signUpInput struct {
 	Username string `json:"username"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
@@ -22,6 +24,10 @@ type signInInput struct {
 	Password string `json:"password"`
 }
 
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 func (a *API) SignUp(w http.ResponseWriter, r *http.Request) {
 	var s signUpInput
 
@@ -32,28 +38,52 @@ func (a *API) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashed, err := bcrypt.Hash(s.Password)
+	if s.Email == "" {
+		server.ErrorJSON(w, r, http.StatusBadRequest, service.ErrInvalidEmail)
+		return
+	}
+	err = a.users.ValidateNewUser(domain.User{Username: s.Username, Email: s.Email})
 	if err != nil {
-		a.log.Errorf("user sign up, bcrypt hashing error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	hashed, err := a.passwords.Hash(s.Password, s.Username)
+	if service.IsPasswordPolicyError(err) {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("user sign up, password hashing error: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	_, err = a.users.CreateUser(r.Context(), domain.User{
+	u, err := a.users.CreateUser(r.Context(), domain.User{
 		Username:     s.Username,
+		Email:        s.Email,
 		PasswordHash: hashed,
 	})
-	if err == service.ErrUserAlreadyExists {
+	if err == service.ErrUserAlreadyExists || err == service.ErrEmailAlreadyUsed {
 		a.log.Warnf("user sign up, user already exist error: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusBadRequest, err)
 		return
 	}
+	if err == service.ErrInvalidEmail || err == service.ErrInvalidUsername {
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		a.log.Errorf("user sign up, create user error: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
+	err = a.accounts.SendVerificationEmail(r.Context(), *u)
+	if err != nil {
+		a.log.Errorf("user sign up, send verification email error: %s", err.Error())
+	}
+
 	server.ResponseJSONWithCode(w, r, http.StatusOK, "ok")
 }
 
@@ -67,25 +97,119 @@ func (a *API) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := a.users.GetUser(r.Context(), s.Username)
-	if err == service.ErrNotFound {
-		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+	ip := clientIP(r)
+	wait, err := a.throttle.Check(r.Context(), s.Username, ip)
+	if err == service.ErrTooManyAttempts {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		server.ErrorJSON(w, r, http.StatusTooManyRequests, err)
 		return
 	}
 	if err != nil {
+		a.log.Errorf("user sign in: check login throttle error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	u, err := a.users.GetUser(r.Context(), s.Username)
+	if err != nil && err != service.ErrForbidden {
 		a.log.Errorf("user sign in: get user error: %s", err.Error())
 		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = bcrypt.Compare(u.PasswordHash, s.Password)
+	// Unknown usernames still pay for a hash comparison, so response timing
+	// does not reveal which accounts exist.
+	userID := 0
+	if u != nil {
+		userID = u.ID
+	} else {
+		a.passwords.VerifyDummy(s.Password)
+	}
+	if u == nil || !a.passwords.Verify(r.Context(), u, s.Password) {
+		if err := a.throttle.RecordFailure(r.Context(), s.Username, ip, userID); err != nil {
+			a.log.Errorf("user sign in: record failure error: %s", err.Error())
+		}
+		server.ErrorJSON(w, r, http.StatusUnauthorized, service.ErrForbidden)
+		return
+	}
+
+	if err := a.throttle.RecordSuccess(r.Context(), s.Username); err != nil {
+		a.log.Errorf("user sign in: record success error: %s", err.Error())
+	}
+
+	if u.Email != "" && !u.EmailVerified {
+		server.ErrorJSON(w, r, http.StatusForbidden, service.ErrEmailNotVerified)
+		return
+	}
+
+	if u.TOTPEnabled {
+		challenge, err := a.mfa.StartChallenge(r.Context(), *u)
+		if err != nil {
+			a.log.Errorf("user sign in: start mfa challenge error: %s", err.Error())
+			server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		server.ResponseJSONWithCode(w, r, http.StatusOK, challenge)
+		return
+	}
+
+	tokens, err := a.startSession(w, r, u)
 	if err != nil {
-		server.ErrorJSON(w, r, http.StatusUnauthorized, errors.New("wrong username or password"))
+		a.log.Errorf("user sign in: start session error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	signer := sign.NewSigner(a.cfg.Auth.Secret)
-	http.SetCookie(w, cookie.NewIDCookie(u.Username, signer.EncodeBase64(signer.Sign(u.Username))).Cookie)
+	server.ResponseJSONWithCode(w, r, http.StatusOK, tokens)
+}
+
+func (a *API) Refresh(w http.ResponseWriter, r *http.Request) {
+	var s refreshInput
 
-	server.ResponseJSONWithCode(w, r, http.StatusOK, "ok")
+	err := json.NewDecoder(r.Body).Decode(&s)
+	if err != nil {
+		a.log.Warnf("token refresh, decoder error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	tokens, err := a.tokens.RefreshTokens(r.Context(), s.RefreshToken)
+	if err == service.ErrInvalidToken || err == service.ErrTokenReused || err == service.ErrInvalidSession {
+		server.ErrorJSON(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		a.log.Errorf("token refresh error: %s", err.Error())
+		server.ErrorJSON(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	server.ResponseJSONWithCode(w, r, http.StatusOK, tokens)
+}
+
+// startSession opens a server-side session for the user, sets the idCookie
+// pointing at it and issues a token pair bound to the same session.
+func (a *API) startSession(w http.ResponseWriter, r *http.Request, u *domain.User) (*domain.TokenPair, error) {
+	session, sessionToken, err := a.sessions.CreateSession(r.Context(), *u, clientIP(r), r.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge := int(a.sessions.TTL().Seconds())
+	secure := a.cfg.HTTP.SecureCookies
+	http.SetCookie(w, cookie.NewIDCookie(sessionToken, a.signer.Sign(sessionToken), maxAge, secure).Cookie)
+	http.SetCookie(w, cookie.NewCSRFCookie(csrf.Token(a.signer, sessionToken), maxAge, secure))
+
+	return a.tokens.IssueTokens(r.Context(), *u, session.ID)
+}
+
+// clientIP is the address the request came from. Forwarding headers only
+// count when a trusted proxy sent them, see middleware.RealIP, so the
+// address can key throttles.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
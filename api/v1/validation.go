@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/service"
+	"github.com/scraletteykt/my-blog/pkg/server"
+	"net/http"
+)
+
+// validationErrorJSON writes a 400 listing every invalid field when err is a
+// service.ValidationError and reports whether it did so.
+func validationErrorJSON(w http.ResponseWriter, r *http.Request, err error) bool {
+	var verr *service.ValidationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+	details := make([]server.ErrorDetail, 0, len(verr.Fields))
+	for _, f := range verr.Fields {
+		details = append(details, server.ErrorDetail{Fields: []string{f.Field}, Message: f.Message})
+	}
+	server.ErrorJSON(w, r, http.StatusBadRequest, verr, details...)
+	return true
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	_ "github.com/lib/pq"
 	apiv1 "github.com/scraletteykt/my-blog/api/v1"
 	"github.com/scraletteykt/my-blog/internal/config"
 	"github.com/scraletteykt/my-blog/internal/repository"
 	"github.com/scraletteykt/my-blog/internal/service"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/mailer"
 	"github.com/scraletteykt/my-blog/pkg/server"
+	"github.com/scraletteykt/my-blog/pkg/sign"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	log := logger.NewLogger()
 
@@ -32,13 +42,67 @@ func main() {
 	}
 
 	repo := repository.NewRepositories(dbConn, log)
-	users := service.NewUsersService(*repo.Users, log)
-	posts := service.NewPostsService(*repo.Posts, *repo.Tags, *repo.PostsTags, log)
-	tags := service.NewTagsService(*repo.Tags, log)
-	api := apiv1.NewAPI(cfg, users, posts, tags, log)
+	m, err := mailer.New(cfg.Mail, log)
+	if err != nil {
+		log.Fatalf("error initializing mailer: %s", err.Error())
+	}
+
+	keys, err := sign.LoadKeyRing(sign.KeySource{
+		Active: cfg.Auth.Keys.Active,
+		Keys:   cfg.Auth.Keys.Secrets,
+		File:   cfg.Auth.Keys.File,
+	})
+	if err != nil {
+		log.Fatalf("error loading signing keys: %s", err.Error())
+	}
+	go watchKeys(keys, cfg.Auth.Keys.ReloadInterval, log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	services := service.NewServices(repo, cfg, keys, m, log)
+	go services.Publisher.Run(ctx)
+	api := apiv1.NewAPI(cfg, services, keys, log)
 	srv := server.NewServer()
 
-	if err := srv.Run(cfg, api.Router()); err != nil {
-		log.Fatalf("error occured while running http server: %s", err.Error())
+	go func() {
+		if err := srv.Run(cfg, api.Router()); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("error occured while running http server: %s", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Stop(shutdownCtx); err != nil {
+		log.Errorf("error shutting down http server: %s", err.Error())
+	}
+}
+
+// watchKeys reloads the signing keys on SIGHUP and, when interval is set,
+// periodically. A failed reload keeps the previous keys.
+func watchKeys(keys *sign.KeyRing, interval time.Duration, log logger.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	active, _ := keys.Active()
+	for {
+		select {
+		case <-hup:
+		case <-tick:
+		}
+		if err := keys.Reload(); err != nil {
+			log.Errorf("error reloading signing keys: %s", err.Error())
+			continue
+		}
+		if id, _ := keys.Active(); id != active {
+			log.Infof("signing keys reloaded, active key %s", id)
+			active = id
+		}
 	}
 }
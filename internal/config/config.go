@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/scraletteykt/my-blog/pkg/logger"
 	"github.com/spf13/viper"
+	"net"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -12,6 +15,46 @@ const (
 	defaultHTTPPort               = "8000"
 	defaultHTTPRWTimeout          = 10 * time.Second
 	defaultHTTPMaxHeaderMegabytes = 1
+	defaultAccessTokenTTL         = 15 * time.Minute
+	defaultRefreshTokenTTL        = 30 * 24 * time.Hour
+	defaultSessionTTL             = 30 * 24 * time.Hour
+	defaultVerifyEmailTTL         = 48 * time.Hour
+	defaultPasswordResetTTL       = time.Hour
+	defaultMailDriver             = "log"
+	defaultTOTPIssuer             = "My Blog"
+	defaultMFAChallengeTTL        = 5 * time.Minute
+	defaultAPITokenTTL            = 90 * 24 * time.Hour
+	defaultAPITokenMaxTTL         = 365 * 24 * time.Hour
+	defaultLockoutFreeAttempts    = 3
+	defaultLockoutThreshold       = 10
+	defaultLockoutIPFreeAttempts  = 20
+	defaultLockoutIPThreshold     = 100
+	defaultLockoutBaseDelay       = time.Second
+	defaultLockoutMaxDelay        = 5 * time.Minute
+	defaultLockoutDuration        = 15 * time.Minute
+	defaultLockoutWindow          = time.Hour
+	defaultLockoutMailPerAddress  = 3
+	defaultLockoutMailPerIP       = 20
+	defaultOIDCDefaultRole        = "author"
+	defaultPasswordAlgorithm      = "argon2id"
+	defaultBcryptCost             = 11
+	defaultArgon2Memory           = 64 * 1024
+	defaultArgon2Iterations       = 3
+	defaultArgon2Parallelism      = 2
+	defaultArgon2SaltLength       = 16
+	defaultArgon2KeyLength        = 32
+	defaultPasswordMinLength      = 8
+	defaultPasswordMaxLength      = 128
+	defaultWordsPerMinute         = 200
+	defaultCJKCharsPerMinute      = 500
+	defaultCodeWordsPerMinute     = 100
+	defaultImageSeconds           = 12
+	defaultExcerptLength          = 200
+	defaultPublisherInterval      = 30 * time.Second
+	defaultPublisherBatchSize     = 50
+	defaultSearchLanguage         = "english"
+
+	DefaultKeyID = "default"
 )
 
 type (
@@ -19,10 +62,107 @@ type (
 		Auth     AuthConfig
 		HTTP     HTTPConfig
 		Postgres PostgresConfig
+		Mail     MailConfig
+		Content  ContentConfig
+	}
+
+	ContentConfig struct {
+		Sanitizer     SanitizerConfig   `mapstructure:"sanitizer"`
+		ReadingTime   ReadingTimeConfig `mapstructure:"readingTime"`
+		ExcerptLength int               `mapstructure:"excerptLength"`
+		Publisher     PublisherConfig   `mapstructure:"publisher"`
+		// SearchLanguage is the text search configuration new posts are
+		// indexed with and queries are parsed with.
+		SearchLanguage string `mapstructure:"searchLanguage"`
+	}
+
+	PublisherConfig struct {
+		Interval  time.Duration `mapstructure:"interval"`
+		BatchSize int           `mapstructure:"batchSize"`
+	}
+
+	ReadingTimeConfig struct {
+		WordsPerMinute     int `mapstructure:"wordsPerMinute"`
+		CJKCharsPerMinute  int `mapstructure:"cjkCharsPerMinute"`
+		CodeWordsPerMinute int `mapstructure:"codeWordsPerMinute"`
+		ImageSeconds       int `mapstructure:"imageSeconds"`
+	}
+
+	SanitizerConfig struct {
+		URLSchemes []string            `mapstructure:"urlSchemes"`
+		Elements   []string            `mapstructure:"elements"`
+		Attributes map[string][]string `mapstructure:"attributes"`
 	}
 
 	AuthConfig struct {
-		Secret string
+		Keys             KeysConfig     `mapstructure:"keys"`
+		AccessTokenTTL   time.Duration  `mapstructure:"accessTokenTTL"`
+		RefreshTokenTTL  time.Duration  `mapstructure:"refreshTokenTTL"`
+		SessionTTL       time.Duration  `mapstructure:"sessionTTL"`
+		VerifyEmailTTL   time.Duration  `mapstructure:"verifyEmailTTL"`
+		PasswordResetTTL time.Duration  `mapstructure:"passwordResetTTL"`
+		TOTPIssuer       string         `mapstructure:"totpIssuer"`
+		MFAChallengeTTL  time.Duration  `mapstructure:"mfaChallengeTTL"`
+		APITokenTTL      time.Duration  `mapstructure:"apiTokenTTL"`
+		APITokenMaxTTL   time.Duration  `mapstructure:"apiTokenMaxTTL"`
+		Lockout          LockoutConfig  `mapstructure:"lockout"`
+		OIDC             OIDCConfig     `mapstructure:"oidc"`
+		Password         PasswordConfig `mapstructure:"password"`
+	}
+
+	PasswordConfig struct {
+		Algorithm     string       `mapstructure:"algorithm"`
+		BcryptCost    int          `mapstructure:"bcryptCost"`
+		Argon2        Argon2Config `mapstructure:"argon2"`
+		MinLength     int          `mapstructure:"minLength"`
+		MaxLength     int          `mapstructure:"maxLength"`
+		BlocklistFile string       `mapstructure:"blocklistFile"`
+	}
+
+	Argon2Config struct {
+		Memory      uint32 `mapstructure:"memory"`
+		Iterations  uint32 `mapstructure:"iterations"`
+		Parallelism uint8  `mapstructure:"parallelism"`
+		SaltLength  uint32 `mapstructure:"saltLength"`
+		KeyLength   uint32 `mapstructure:"keyLength"`
+	}
+
+	// KeysConfig lists the signing keys. Secrets come from SECRET_KEYS
+	// ("id=secret,id=secret") and SECRET_KEY, which is registered under
+	// DefaultKeyID; File may add keys and override Active.
+	KeysConfig struct {
+		Active         string        `mapstructure:"active"`
+		File           string        `mapstructure:"file"`
+		ReloadInterval time.Duration `mapstructure:"reloadInterval"`
+		Secrets        map[string]string
+	}
+
+	LockoutConfig struct {
+		FreeAttempts       int           `mapstructure:"freeAttempts"`
+		LockoutThreshold   int           `mapstructure:"threshold"`
+		IPFreeAttempts     int           `mapstructure:"ipFreeAttempts"`
+		IPLockoutThreshold int           `mapstructure:"ipThreshold"`
+		BaseDelay          time.Duration `mapstructure:"baseDelay"`
+		MaxDelay           time.Duration `mapstructure:"maxDelay"`
+		LockoutDuration    time.Duration `mapstructure:"duration"`
+		Window             time.Duration `mapstructure:"window"`
+		// MailPerAddress and MailPerIP cap the verification and password
+		// reset emails sent within a window to one address and on behalf of
+		// one client.
+		MailPerAddress int `mapstructure:"mailPerAddress"`
+		MailPerIP      int `mapstructure:"mailPerIP"`
+	}
+
+	OIDCConfig struct {
+		Enabled       bool   `mapstructure:"enabled"`
+		Issuer        string `mapstructure:"issuer"`
+		ClientID      string `mapstructure:"clientID"`
+		ClientSecret  string
+		RedirectURL   string   `mapstructure:"redirectURL"`
+		Scopes        []string `mapstructure:"scopes"`
+		AutoProvision bool     `mapstructure:"autoProvision"`
+		DefaultRole   string   `mapstructure:"defaultRole"`
+		PostLoginURL  string   `mapstructure:"postLoginURL"`
 	}
 
 	HTTPConfig struct {
@@ -30,6 +170,10 @@ type (
 		ReadTimeout        time.Duration `mapstructure:"readTimeout"`
 		WriteTimeout       time.Duration `mapstructure:"writeTimeout"`
 		MaxHeaderMegabytes int           `mapstructure:"maxHeaderBytes"`
+		SecureCookies      bool          `mapstructure:"secureCookies"`
+		// TrustedProxies are the addresses or CIDR ranges of reverse
+		// proxies whose X-Forwarded-For and X-Real-IP headers are believed.
+		TrustedProxies []string `mapstructure:"trustedProxies"`
 	}
 
 	PostgresConfig struct {
@@ -40,6 +184,21 @@ type (
 		DBName   string `mapstructure:"dbname"`
 		SSLMode  string `sslmode:"host"`
 	}
+
+	MailConfig struct {
+		Driver  string     `mapstructure:"driver"`
+		From    string     `mapstructure:"from"`
+		BaseURL string     `mapstructure:"baseURL"`
+		FileDir string     `mapstructure:"fileDir"`
+		SMTP    SMTPConfig `mapstructure:"smtp"`
+	}
+
+	SMTPConfig struct {
+		Host     string `mapstructure:"host"`
+		Port     string `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string
+	}
 )
 
 func NewConfig(log logger.Logger) (*Config, error) {
@@ -54,18 +213,43 @@ func NewConfig(log logger.Logger) (*Config, error) {
 		return nil, err
 	}
 
-	cfg.Auth.Secret = os.Getenv("SECRET_KEY")
+	cfg.Auth.Keys.Secrets = parseKeys(os.Getenv("SECRET_KEYS"))
+	if secret := os.Getenv("SECRET_KEY"); secret != "" {
+		if _, ok := cfg.Auth.Keys.Secrets[DefaultKeyID]; !ok {
+			cfg.Auth.Keys.Secrets[DefaultKeyID] = secret
+		}
+	}
 	cfg.Postgres.Password = os.Getenv("DB_PASSWORD")
+	cfg.Mail.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	cfg.Auth.OIDC.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
 	return &cfg, nil
 }
 
 func unmarshal(cfg *Config) error {
+	if err := viper.UnmarshalKey("auth", &cfg.Auth); err != nil {
+		return err
+	}
 	if err := viper.UnmarshalKey("http", &cfg.HTTP); err != nil {
 		return err
 	}
+	for _, p := range cfg.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return fmt.Errorf("http.trustedProxies: %q is neither an IP address nor a CIDR range", p)
+		}
+	}
 	if err := viper.UnmarshalKey("postgres", &cfg.Postgres); err != nil {
 		return err
 	}
+	if err := viper.UnmarshalKey("mail", &cfg.Mail); err != nil {
+		return err
+	}
+	if err := viper.UnmarshalKey("content", &cfg.Content); err != nil {
+		return err
+	}
+	// The publisher would never get past an empty batch.
+	if cfg.Content.Publisher.BatchSize <= 0 {
+		cfg.Content.Publisher.BatchSize = defaultPublisherBatchSize
+	}
 	return nil
 }
 
@@ -88,4 +272,55 @@ func populateDefaults() {
 	viper.SetDefault("http.maxHeaderBytes", defaultHTTPMaxHeaderMegabytes)
 	viper.SetDefault("http.readTimeout", defaultHTTPRWTimeout)
 	viper.SetDefault("http.writeTimeout", defaultHTTPRWTimeout)
+	viper.SetDefault("auth.accessTokenTTL", defaultAccessTokenTTL)
+	viper.SetDefault("auth.refreshTokenTTL", defaultRefreshTokenTTL)
+	viper.SetDefault("auth.sessionTTL", defaultSessionTTL)
+	viper.SetDefault("auth.verifyEmailTTL", defaultVerifyEmailTTL)
+	viper.SetDefault("auth.passwordResetTTL", defaultPasswordResetTTL)
+	viper.SetDefault("auth.totpIssuer", defaultTOTPIssuer)
+	viper.SetDefault("auth.mfaChallengeTTL", defaultMFAChallengeTTL)
+	viper.SetDefault("auth.apiTokenTTL", defaultAPITokenTTL)
+	viper.SetDefault("auth.apiTokenMaxTTL", defaultAPITokenMaxTTL)
+	viper.SetDefault("auth.lockout.freeAttempts", defaultLockoutFreeAttempts)
+	viper.SetDefault("auth.lockout.threshold", defaultLockoutThreshold)
+	viper.SetDefault("auth.lockout.ipFreeAttempts", defaultLockoutIPFreeAttempts)
+	viper.SetDefault("auth.lockout.ipThreshold", defaultLockoutIPThreshold)
+	viper.SetDefault("auth.lockout.baseDelay", defaultLockoutBaseDelay)
+	viper.SetDefault("auth.lockout.maxDelay", defaultLockoutMaxDelay)
+	viper.SetDefault("auth.lockout.duration", defaultLockoutDuration)
+	viper.SetDefault("auth.lockout.window", defaultLockoutWindow)
+	viper.SetDefault("auth.lockout.mailPerAddress", defaultLockoutMailPerAddress)
+	viper.SetDefault("auth.lockout.mailPerIP", defaultLockoutMailPerIP)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email"})
+	viper.SetDefault("auth.oidc.defaultRole", defaultOIDCDefaultRole)
+	viper.SetDefault("auth.password.algorithm", defaultPasswordAlgorithm)
+	viper.SetDefault("auth.password.bcryptCost", defaultBcryptCost)
+	viper.SetDefault("auth.password.argon2.memory", defaultArgon2Memory)
+	viper.SetDefault("auth.password.argon2.iterations", defaultArgon2Iterations)
+	viper.SetDefault("auth.password.argon2.parallelism", defaultArgon2Parallelism)
+	viper.SetDefault("auth.password.argon2.saltLength", defaultArgon2SaltLength)
+	viper.SetDefault("auth.password.argon2.keyLength", defaultArgon2KeyLength)
+	viper.SetDefault("auth.password.minLength", defaultPasswordMinLength)
+	viper.SetDefault("auth.password.maxLength", defaultPasswordMaxLength)
+	viper.SetDefault("mail.driver", defaultMailDriver)
+	viper.SetDefault("content.sanitizer.urlSchemes", []string{"http", "https", "mailto"})
+	viper.SetDefault("content.readingTime.wordsPerMinute", defaultWordsPerMinute)
+	viper.SetDefault("content.readingTime.cjkCharsPerMinute", defaultCJKCharsPerMinute)
+	viper.SetDefault("content.readingTime.codeWordsPerMinute", defaultCodeWordsPerMinute)
+	viper.SetDefault("content.readingTime.imageSeconds", defaultImageSeconds)
+	viper.SetDefault("content.excerptLength", defaultExcerptLength)
+	viper.SetDefault("content.publisher.interval", defaultPublisherInterval)
+	viper.SetDefault("content.publisher.batchSize", defaultPublisherBatchSize)
+	viper.SetDefault("content.searchLanguage", defaultSearchLanguage)
+}
+
+func parseKeys(s string) map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			keys[kv[0]] = kv[1]
+		}
+	}
+	return keys
 }
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type AccountExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	Account    *User           `json:"account"`
+	Profile    *Profile        `json:"profile"`
+	Posts      []*Post         `json:"posts"`
+	Tags       []*Tag          `json:"tags"`
+	Sessions   []*Session      `json:"sessions"`
+	APITokens  []*APIToken     `json:"api_tokens"`
+	Identities []*UserIdentity `json:"identities"`
+}
+
+type UserIdentity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeletionRequest struct {
+	UserID      int       `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// DeleteAccount removes a user. Posts are moved to TransferTo when it is
+// set, otherwise the account is anonymized and keeps its posts.
+type DeleteAccount struct {
+	UserID     int
+	TransferTo int
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+type APIToken struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateAPIToken struct {
+	UserID    int
+	Name      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
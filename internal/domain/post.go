@@ -8,17 +8,28 @@ type Post struct {
 	ID          int       `json:"id"`
 	UserID      int       `json:"user_id"`
 	ReadingTime int       `json:"reading_time"`
+	WordCount   int       `json:"word_count"`
+	Excerpt     string    `json:"excerpt"`
 	Status      int       `json:"status"`
 	Title       string    `json:"title"`
 	Subtitle    string    `json:"subtitle"`
 	ImageURL    string    `json:"image_url"`
 	Content     string    `json:"content"`
+	Format      string    `json:"format"`
+	ContentHTML string    `json:"content_html"`
 	Slug        string    `json:"slug"`
+	Views       int64     `json:"views"`
 	PublishedAt time.Time `json:"published_at"`
+	PublishAt   time.Time `json:"publish_at"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   time.Time `json:"deleted_at"`
+	Author      *Author   `json:"author,omitempty"`
 	Tags        []*Tag    `json:"tags"`
+	// ManualReadingTime and ManualExcerpt are set when the author overrode
+	// the computed values.
+	ManualReadingTime bool `json:"-"`
+	ManualExcerpt     bool `json:"-"`
 }
 
 type CreatePost struct {
@@ -26,8 +37,10 @@ type CreatePost struct {
 	ReadingTime int
 	Title       string
 	Subtitle    string
+	Excerpt     string
 	ImageURL    string
 	Content     string
+	Format      string
 	Slug        string
 	TagIDs      []int
 }
@@ -38,8 +51,10 @@ type UpdatePost struct {
 	Status      int
 	Title       string
 	Subtitle    string
+	Excerpt     string
 	ImageURL    string
 	Content     string
+	Format      string
 	Slug        string
 	TagIDs      []int
 }
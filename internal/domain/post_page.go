@@ -0,0 +1,76 @@
+package domain
+
+const (
+	PostSortCreated   = "created"
+	PostSortPublished = "published"
+	PostSortUpdated   = "updated"
+	PostSortTitle     = "title"
+	PostSortPopular   = "popular"
+	// PostSortScheduled orders scheduled posts by when they go out. It is
+	// not offered to clients.
+	PostSortScheduled = "scheduled"
+)
+
+// PostSort orders a listing by one of the PostSort fields, descending
+// unless Ascending is set.
+type PostSort struct {
+	Field     string
+	Ascending bool
+}
+
+const (
+	TagsMatchAny = "any"
+	TagsMatchAll = "all"
+)
+
+// PostFilter narrows a listing down. The values are taken as the client
+// sent them and checked by the service, so that every invalid one can be
+// reported at once.
+type PostFilter struct {
+	// Tags are tag slugs, matched according to TagsMatch.
+	Tags      []string
+	TagsMatch string
+	// Author is a username.
+	Author string
+	// From and To bound the publication date, as a date or an RFC 3339
+	// time. A date in To includes that whole day.
+	From     string
+	To       string
+	Status   string
+	HasImage string
+}
+
+// PostListing selects a page of posts, the published ones unless the
+// filter asks for another status. Cursor is empty for the first page,
+// otherwise one of the cursors handed out with another page.
+type PostListing struct {
+	TagID     int
+	Filter    PostFilter
+	Sort      PostSort
+	Cursor    string
+	Limit     uint64
+	WithTotal bool
+}
+
+// PostPage is a page of posts with the cursors of the neighbouring pages.
+// Total is only filled in when asked for.
+type PostPage struct {
+	Items []*Post `json:"items"`
+	Next  string  `json:"next,omitempty"`
+	Prev  string  `json:"prev,omitempty"`
+	Total *int    `json:"total,omitempty"`
+}
+
+type PostStatusCounts struct {
+	Draft     int `json:"draft"`
+	Scheduled int `json:"scheduled"`
+	Published int `json:"published"`
+	Trashed   int `json:"trashed"`
+}
+
+// PostDashboard is a page of an author's posts along with how many of
+// them there are in each status.
+type PostDashboard struct {
+	*PostPage
+	Counts PostStatusCounts `json:"counts"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// PostRevision is a post as it was before an update. EditedAt and Editor
+// describe the edit that produced this version.
+type PostRevision struct {
+	ID             int       `json:"id"`
+	PostID         int       `json:"post_id"`
+	EditorID       int       `json:"editor_id,omitempty"`
+	EditorUsername string    `json:"editor_username,omitempty"`
+	Status         int       `json:"status"`
+	Title          string    `json:"title"`
+	Subtitle       string    `json:"subtitle"`
+	ImageURL       string    `json:"image_url"`
+	Content        string    `json:"content,omitempty"`
+	Format         string    `json:"format"`
+	Slug           string    `json:"slug"`
+	Tags           []*Tag    `json:"tags"`
+	EditedAt       time.Time `json:"edited_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RevisionDiff struct {
+	PostID int    `json:"post_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Diff   string `json:"diff"`
+}
@@ -2,6 +2,7 @@ package domain
 
 const (
 	PostStatusDraft     = 10
+	PostStatusScheduled = 15
 	PostStatusPublished = 20
 	PostStatusDeleted   = 30
 )
@@ -0,0 +1,27 @@
+package domain
+
+type Profile struct {
+	UserID      int               `json:"-"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"display_name"`
+	Bio         string            `json:"bio"`
+	AvatarURL   string            `json:"avatar_url"`
+	Website     string            `json:"website"`
+	SocialLinks map[string]string `json:"social_links"`
+}
+
+type UpdateProfile struct {
+	UserID      int
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+	Website     string
+	SocialLinks map[string]string
+}
+
+type Author struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+const (
+	RedirectKindPost = "post"
+	RedirectKindTag  = "tag"
+)
+
+// SlugRedirect points a previous slug at the post or tag that used it.
+type SlugRedirect struct {
+	ID       int    `json:"id"`
+	Kind     string `json:"kind"`
+	Slug     string `json:"slug"`
+	TargetID int    `json:"target_id"`
+	// TargetSlug is the current slug of the target, empty once the target
+	// is gone and the redirect is stale.
+	TargetSlug string    `json:"target_slug"`
+	Stale      bool      `json:"stale"`
+	CreatedAt  time.Time `json:"created_at"`
+}
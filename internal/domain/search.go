@@ -0,0 +1,17 @@
+package domain
+
+type PostSearch struct {
+	Query    string
+	AuthorID int
+	TagSlug  string
+	Limit    uint64
+	Offset   uint64
+}
+
+// PostSearchResult is a matching post with its relevance and an HTML
+// snippet of the content where matches are wrapped in <mark>.
+type PostSearchResult struct {
+	*Post
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
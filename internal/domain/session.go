@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type TokenPair struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresIn             int       `json:"expires_in"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
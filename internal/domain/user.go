@@ -1,7 +1,28 @@
 package domain
 
 type User struct {
-	ID           int    `json:"id,omitempty" db:"id"`
-	Username     string `json:"username" db:"username"`
-	PasswordHash string `json:"password" db:"password_hash"`
+	ID            int    `json:"id,omitempty" db:"id"`
+	Username      string `json:"username" db:"username"`
+	PasswordHash  string `json:"-" db:"password_hash"`
+	Role          string `json:"role" db:"role"`
+	Email         string `json:"email,omitempty" db:"email"`
+	EmailVerified bool   `json:"email_verified" db:"email_verified"`
+	TOTPSecret    string `json:"-" db:"totp_secret"`
+	TOTPEnabled   bool   `json:"totp_enabled" db:"totp_enabled"`
+	Deleted       bool   `json:"deleted,omitempty" db:"deleted"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type UpdateUserRole struct {
+	ID   int
+	Role string
 }
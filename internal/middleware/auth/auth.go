@@ -1,32 +1,76 @@
 package auth
 
 import (
+	"errors"
 	"github.com/scraletteykt/my-blog/internal/service"
 	"github.com/scraletteykt/my-blog/pkg/auth"
 	"github.com/scraletteykt/my-blog/pkg/cookie"
+	"github.com/scraletteykt/my-blog/pkg/server"
 	signer "github.com/scraletteykt/my-blog/pkg/sign"
 	"net/http"
+	"strings"
+)
+
+const (
+	bearerPrefix = "Bearer "
+	tokenPrefix  = "Token "
+)
+
+var (
+	errUnauthorized = errors.New("unauthorized access")
+	errForbidden    = errors.New("access denied")
 )
 
 type Config struct {
-	Secret string
+	Keys *signer.KeyRing
 }
 
 type Auth struct {
-	secret string
-	users  *service.UsersService
+	signer    *signer.Signer
+	sessions  *service.SessionsService
+	tokens    *service.TokensService
+	apiTokens *service.APITokensService
 }
 
-func New(cfg *Config, users *service.UsersService) *Auth {
+func New(cfg *Config, sessions *service.SessionsService, tokens *service.TokensService, apiTokens *service.APITokensService) *Auth {
 	return &Auth{
-		secret: cfg.Secret,
-		users:  users,
+		signer:    signer.NewSigner(cfg.Keys),
+		sessions:  sessions,
+		tokens:    tokens,
+		apiTokens: apiTokens,
 	}
 }
 
 func (a *Auth) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s := signer.NewSigner(a.secret)
+		if credential, ok := parseAuthorization(r.Header.Get("Authorization")); ok {
+			var (
+				u   auth.User
+				err error
+			)
+			if service.IsAPIToken(credential) {
+				u, err = a.apiTokens.Authenticate(r.Context(), credential)
+				u.Source = auth.SourceAPIToken
+			} else {
+				u, err = a.tokens.ParseAccessToken(credential)
+				u.Source = auth.SourceBearer
+				// Access tokens outlive a sign-out or a revoked session
+				// unless the session is checked on every request.
+				if err == nil {
+					var active bool
+					if active, err = a.sessions.IsActive(r.Context(), u.SessionID); err == nil && !active {
+						err = service.ErrInvalidSession
+					}
+				}
+			}
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), u)))
+			return
+		}
+
 		httpCookie, err := r.Cookie(cookie.IDCookieName)
 
 		if err != nil {
@@ -35,14 +79,16 @@ func (a *Auth) Handler(next http.Handler) http.Handler {
 		}
 
 		idCookie, err := cookie.ParseFromCookie(httpCookie)
-		sign, decodeErr := s.DecodeBase64(idCookie.Sign)
-
-		if err != nil || decodeErr != nil || !s.Verify(sign, idCookie.Username) {
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !a.signer.Verify(idCookie.Sign, idCookie.SessionToken) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		u, err := a.users.GetUser(r.Context(), idCookie.Username)
+		u, session, err := a.sessions.Authenticate(r.Context(), idCookie.SessionToken)
 
 		if err != nil {
 			next.ServeHTTP(w, r)
@@ -50,8 +96,59 @@ func (a *Auth) Handler(next http.Handler) http.Handler {
 		}
 
 		next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), auth.User{
-			ID:       u.ID,
-			Username: u.Username,
+			ID:        u.ID,
+			Username:  u.Username,
+			Role:      u.Role,
+			SessionID: session.ID,
+			Source:    auth.SourceCookie,
 		})))
 	})
 }
+
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.FromContext(r.Context()).IsAuthenticated() {
+			server.ErrorJSON(w, r, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func Require(p auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u := auth.FromContext(r.Context())
+			if !u.IsAuthenticated() {
+				server.ErrorJSON(w, r, http.StatusUnauthorized, errUnauthorized)
+				return
+			}
+			if !u.Can(p) {
+				server.ErrorJSON(w, r, http.StatusForbidden, errForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireUnscoped(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.FromContext(r.Context()).IsScoped() {
+			server.ErrorJSON(w, r, http.StatusForbidden, errForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func parseAuthorization(header string) (string, bool) {
+	switch {
+	case strings.HasPrefix(header, bearerPrefix):
+		return strings.TrimPrefix(header, bearerPrefix), true
+	case strings.HasPrefix(header, tokenPrefix) && service.IsAPIToken(strings.TrimPrefix(header, tokenPrefix)):
+		return strings.TrimPrefix(header, tokenPrefix), true
+	default:
+		return "", false
+	}
+}
@@ -2,16 +2,103 @@ package middleware
 
 import (
 	mw "github.com/go-chi/chi/v5/middleware"
+	"net"
 	"net/http"
+	"strings"
 )
 
-func Middleware() []func(handler http.Handler) http.Handler {
+func Middleware(trustedProxies []string) []func(handler http.Handler) http.Handler {
 	mws := []func(handler http.Handler) http.Handler{
 		mw.RequestID,
-		mw.RealIP,
+		RealIP(trustedProxies),
 		mw.Logger,
 		mw.Recoverer,
 	}
 
 	return mws
 }
+
+// RealIP replaces the remote address of requests coming from one of the
+// trusted proxies, given as IP addresses or CIDR ranges, with the client
+// address they forwarded. Anyone can send forwarding headers, so they are
+// ignored on requests that reach the server directly.
+func RealIP(trustedProxies []string) func(handler http.Handler) http.Handler {
+	trusted := ParseProxies(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ParseProxies turns IP addresses and CIDR ranges into networks, skipping
+// entries that are neither.
+func ParseProxies(proxies []string) []*net.IPNet {
+	out := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			out = append(out, n)
+			continue
+		}
+		ip := net.ParseIP(p)
+		if ip == nil {
+			continue
+		}
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return out
+}
+
+// forwardedIP returns the client address forwarded by a trusted proxy, or
+// an empty string. Each proxy appends the address it got the request from
+// to X-Forwarded-For, so the client is the rightmost entry that isn't one
+// of the trusted proxies.
+func forwardedIP(r *http.Request, trusted []*net.IPNet) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrusted(net.ParseIP(peer), trusted) {
+		return ""
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !isTrusted(ip, trusted) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
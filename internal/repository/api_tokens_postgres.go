@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const (
+	apiTokensTable   = "api_tokens"
+	apiTokensColumns = "id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at"
+)
+
+type APIToken struct {
+	ID         int            `db:"id"`
+	UserID     int            `db:"user_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	TokenHash  string         `db:"token_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+}
+
+type CreateAPIToken struct {
+	UserID    int
+	Name      string
+	Prefix    string
+	TokenHash string
+	Scopes    []string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type APITokensRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewAPITokensRepo(db *sqlx.DB, log logger.Logger) *APITokensRepo {
+	return &APITokensRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *APITokensRepo) CreateAPIToken(ctx context.Context, createToken CreateAPIToken) (int, error) {
+	var id int
+	query, args, _ := squirrel.Insert(apiTokensTable).
+		SetMap(map[string]interface{}{
+			"user_id":    createToken.UserID,
+			"name":       createToken.Name,
+			"prefix":     createToken.Prefix,
+			"token_hash": createToken.TokenHash,
+			"scopes":     pq.StringArray(createToken.Scopes),
+			"expires_at": createToken.ExpiresAt,
+			"created_at": createToken.CreatedAt,
+		}).
+		Suffix("RETURNING \"id\"").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *APITokensRepo) GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error) {
+	var t APIToken
+	query, args, _ := squirrel.Select(apiTokensColumns).
+		From(apiTokensTable).
+		Where("token_hash = ?", tokenHash).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.GetContext(ctx, &t, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *APITokensRepo) GetAPITokensByUser(ctx context.Context, userID int) ([]*APIToken, error) {
+	out := make([]*APIToken, 0)
+	query, args, _ := squirrel.Select(apiTokensColumns).
+		From(apiTokensTable).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *APITokensRepo) TouchAPIToken(ctx context.Context, id int, lastUsedAt time.Time) error {
+	query, args, _ := squirrel.Update(apiTokensTable).
+		Set("last_used_at", lastUsedAt).
+		Where("id = ?", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *APITokensRepo) RevokeUserAPITokens(ctx context.Context, userID int, revokedAt time.Time) error {
+	query, args, _ := squirrel.Update(apiTokensTable).
+		Set("revoked_at", revokedAt).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *APITokensRepo) RevokeAPIToken(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error) {
+	query, args, _ := squirrel.Update(apiTokensTable).
+		Set("revoked_at", revokedAt).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const auditLogTable = "audit_log"
+
+type CreateAuditEntry struct {
+	Action    string
+	UserID    sql.NullInt32
+	IP        string
+	Details   string
+	CreatedAt time.Time
+}
+
+type AuditRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewAuditRepo(db *sqlx.DB, log logger.Logger) *AuditRepo {
+	return &AuditRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *AuditRepo) CreateAuditEntry(ctx context.Context, entry CreateAuditEntry) error {
+	query, args, _ := squirrel.Insert(auditLogTable).
+		SetMap(map[string]interface{}{
+			"action":     entry.Action,
+			"user_id":    entry.UserID,
+			"ip":         entry.IP,
+			"details":    entry.Details,
+			"created_at": entry.CreatedAt,
+		}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
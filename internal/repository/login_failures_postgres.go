@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const loginFailuresTable = "login_failures"
+
+type LoginFailure struct {
+	Key          string       `db:"key"`
+	Failures     int          `db:"failures"`
+	LastFailedAt time.Time    `db:"last_failed_at"`
+	LockedUntil  sql.NullTime `db:"locked_until"`
+}
+
+type LoginFailuresRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewLoginFailuresRepo(db *sqlx.DB, log logger.Logger) *LoginFailuresRepo {
+	return &LoginFailuresRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *LoginFailuresRepo) GetLoginFailures(ctx context.Context, keys []string) ([]*LoginFailure, error) {
+	out := make([]*LoginFailure, 0)
+	query, args, _ := squirrel.Select("key, failures, last_failed_at, locked_until").
+		From(loginFailuresTable).
+		Where(squirrel.Eq{"key": keys}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddLoginFailure increments the failure counter for key, starting over when
+// the previous failure happened before windowStart, and returns the new count.
+func (r *LoginFailuresRepo) AddLoginFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	var failures int
+	query := fmt.Sprintf(`INSERT INTO %[1]s (key, failures, last_failed_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN %[1]s.last_failed_at < $3 THEN 1 ELSE %[1]s.failures + 1 END,
+			last_failed_at = $2
+		RETURNING failures`, loginFailuresTable)
+	if err := r.db.QueryRowContext(ctx, query, key, now, windowStart).Scan(&failures); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (r *LoginFailuresRepo) LockLogin(ctx context.Context, key string, lockedUntil time.Time) error {
+	query, args, _ := squirrel.Update(loginFailuresTable).
+		Set("locked_until", lockedUntil).
+		Where("key = ?", key).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *LoginFailuresRepo) DeleteLoginFailures(ctx context.Context, key string) error {
+	query, args, _ := squirrel.Delete(loginFailuresTable).
+		Where("key = ?", key).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
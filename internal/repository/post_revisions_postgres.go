@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const postRevisionsTable = "post_revisions"
+
+type PostRevision struct {
+	ID             int            `db:"id"`
+	PostID         int            `db:"post_id"`
+	EditorID       sql.NullInt32  `db:"editor_id"`
+	EditorUsername sql.NullString `db:"editor_username"`
+	Status         int            `db:"status"`
+	Title          string         `db:"title"`
+	Subtitle       string         `db:"subtitle"`
+	ImageURL       string         `db:"image_url"`
+	Content        string         `db:"content"`
+	Format         string         `db:"format"`
+	Slug           string         `db:"slug"`
+	// Tags is a JSON array of the tags as they were, see snapshotPost.
+	Tags      []byte    `db:"tags"`
+	EditedAt  time.Time `db:"edited_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type PostRevisionsRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewPostRevisionsRepo(db *sqlx.DB, log logger.Logger) *PostRevisionsRepo {
+	return &PostRevisionsRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// GetRevisions lists the revisions of a post, newest first, without their
+// content.
+func (r *PostRevisionsRepo) GetRevisions(ctx context.Context, postID int) ([]*PostRevision, error) {
+	query, args, _ := r.selectRevisions("'' AS content").
+		Where("r.post_id = ?", postID).
+		OrderBy("r.id DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	out := make([]*PostRevision, 0)
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostRevisionsRepo) GetRevision(ctx context.Context, postID, id int) (*PostRevision, error) {
+	query, args, _ := r.selectRevisions("r.content").
+		Where("r.post_id = ? AND r.id = ?", postID, id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	var revision PostRevision
+	err := r.db.GetContext(ctx, &revision, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (r *PostRevisionsRepo) selectRevisions(content string) squirrel.SelectBuilder {
+	return squirrel.Select(`
+			r.id,
+			r.post_id,
+			r.editor_id,
+			u.username AS editor_username,
+			r.status,
+			r.title,
+			r.subtitle,
+			r.image_url,
+			` + content + `,
+			r.format,
+			r.slug,
+			r.tags,
+			r.edited_at,
+			r.created_at`).
+		From(postRevisionsTable + " r").
+		LeftJoin(usersTable + " u ON u.id = r.editor_id")
+}
+
+// snapshotPost copies the current state of a post and its tags into a new
+// revision.
+func snapshotPost(ctx context.Context, tx *sqlx.Tx, postID int, createdAt time.Time) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (post_id, editor_id, status, title, subtitle, image_url, content, format, slug, tags, edited_at, created_at)
+		SELECT p.id, p.updated_by, COALESCE(p.status, 0), p.title, COALESCE(p.subtitle, ''), COALESCE(p.image_url, ''),
+			COALESCE(p.content, ''), p.format, p.slug,
+			COALESCE((
+				SELECT json_agg(json_build_object('id', t.id, 'name', t.name, 'slug', t.slug) ORDER BY t.id)
+				FROM %s pt JOIN %s t ON t.id = pt.tag_id
+				WHERE pt.post_id = p.id
+			), '[]'),
+			p.updated_at, $2
+		FROM %s p
+		WHERE p.id = $1`, postRevisionsTable, postsTagsTable, tagsTable, postsTable)
+	_, err := tx.ExecContext(ctx, query, postID, createdAt)
+	return err
+}
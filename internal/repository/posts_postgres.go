@@ -10,82 +10,184 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 const (
 	postsTable   = "posts"
+	postsSlugKey = "posts_slug_key"
 	postsPerPage = 30
 )
 
 type Post struct {
-	ID          int          `db:"p_id"`
-	UserID      int          `db:"p_user_id"`
-	ReadingTime int          `db:"p_reading_time"`
-	Status      int          `db:"p_status"`
-	Title       string       `db:"p_title"`
-	Subtitle    string       `db:"p_subtitle"`
-	ImageURL    string       `db:"p_image_url"`
-	Content     string       `db:"p_content"`
-	Slug        string       `db:"p_slug"`
-	PublishedAt sql.NullTime `db:"p_published_at"`
-	CreatedAt   time.Time    `db:"p_created_at"`
-	UpdatedAt   time.Time    `db:"p_updated_at"`
-	DeletedAt   sql.NullTime `db:"p_deleted_at"`
-	Tags        []*Tag
+	ID            int          `db:"p_id"`
+	UserID        int          `db:"p_user_id"`
+	ReadingTime   int          `db:"p_reading_time"`
+	WordCount     int          `db:"p_word_count"`
+	Excerpt       string       `db:"p_excerpt"`
+	ManualReading bool         `db:"p_reading_time_manual"`
+	ManualExcerpt bool         `db:"p_excerpt_manual"`
+	Status        int          `db:"p_status"`
+	Title         string       `db:"p_title"`
+	Subtitle      string       `db:"p_subtitle"`
+	ImageURL      string       `db:"p_image_url"`
+	Content       string       `db:"p_content"`
+	Format        string       `db:"p_format"`
+	ContentHTML   string       `db:"p_content_html"`
+	RenderVersion int          `db:"p_render_version"`
+	Slug          string       `db:"p_slug"`
+	Views         int64        `db:"p_views"`
+	PublishedAt   sql.NullTime `db:"p_published_at"`
+	PublishAt     sql.NullTime `db:"p_publish_at"`
+	CreatedAt     time.Time    `db:"p_created_at"`
+	UpdatedAt     time.Time    `db:"p_updated_at"`
+	DeletedAt     sql.NullTime `db:"p_deleted_at"`
+	Author        *PostAuthor
+	Tags          []*Tag
+}
+
+type PostAuthor struct {
+	Username    string
+	DisplayName string
+	AvatarURL   string
 }
 
 type PostTag struct {
-	ID          int            `db:"p_id"`
-	UserID      int            `db:"p_user_id"`
-	ReadingTime int            `db:"p_reading_time"`
-	Status      int            `db:"p_status"`
-	Title       string         `db:"p_title"`
-	Subtitle    string         `db:"p_subtitle"`
-	ImageURL    string         `db:"p_image_url"`
-	Content     string         `db:"p_content"`
-	Slug        string         `db:"p_slug"`
-	PublishedAt sql.NullTime   `db:"p_published_at"`
-	CreatedAt   time.Time      `db:"p_created_at"`
-	UpdatedAt   time.Time      `db:"p_updated_at"`
-	DeletedAt   sql.NullTime   `db:"p_deleted_at"`
-	TagID       sql.NullInt32  `db:"t_id"`
-	TagName     sql.NullString `db:"t_name"`
-	TagSlug     sql.NullString `db:"t_slug"`
+	ID            int            `db:"p_id"`
+	UserID        int            `db:"p_user_id"`
+	ReadingTime   int            `db:"p_reading_time"`
+	WordCount     int            `db:"p_word_count"`
+	Excerpt       string         `db:"p_excerpt"`
+	ManualReading bool           `db:"p_reading_time_manual"`
+	ManualExcerpt bool           `db:"p_excerpt_manual"`
+	Status        int            `db:"p_status"`
+	Title         string         `db:"p_title"`
+	Subtitle      string         `db:"p_subtitle"`
+	ImageURL      string         `db:"p_image_url"`
+	Content       string         `db:"p_content"`
+	Format        string         `db:"p_format"`
+	ContentHTML   string         `db:"p_content_html"`
+	RenderVersion int            `db:"p_render_version"`
+	Slug          string         `db:"p_slug"`
+	Views         int64          `db:"p_views"`
+	PublishedAt   sql.NullTime   `db:"p_published_at"`
+	PublishAt     sql.NullTime   `db:"p_publish_at"`
+	CreatedAt     time.Time      `db:"p_created_at"`
+	UpdatedAt     time.Time      `db:"p_updated_at"`
+	DeletedAt     sql.NullTime   `db:"p_deleted_at"`
+	Username      sql.NullString `db:"u_username"`
+	DisplayName   sql.NullString `db:"u_display_name"`
+	AvatarURL     sql.NullString `db:"u_avatar_url"`
+	TagID         sql.NullInt32  `db:"t_id"`
+	TagName       sql.NullString `db:"t_name"`
+	TagSlug       sql.NullString `db:"t_slug"`
 }
 
 type PostCriteria struct {
-	ID     int `db:"p_id"`
-	UserID int `db:"p_user_id"`
-	Status int `db:"p_status"`
-	TagID  int `db:"t_id"`
-	Limit  uint64
-	Offset uint64
+	ID     int    `db:"p_id"`
+	UserID int    `db:"p_user_id"`
+	Status int    `db:"p_status"`
+	TagID  int    `db:"t_id"`
+	Slug   string `db:"p_slug"`
+	// IDs limits the result to the given posts.
+	IDs pq.Int64Array `db:"p_ids"`
+	// TagSlugs keeps posts having any of the tags, or all of them when
+	// AllTags is set.
+	TagSlugs pq.StringArray `db:"t_slugs"`
+	AllTags  bool
+	Username string `db:"u_username"`
+	// PublishedFrom and PublishedTo bound the publication time, the upper
+	// bound being exclusive.
+	PublishedFrom time.Time `db:"p_published_from"`
+	PublishedTo   time.Time `db:"p_published_to"`
+	HasImage      *bool
+	// IncludeDeleted lists deleted posts alongside the others.
+	IncludeDeleted bool
+	// Sort is one of the domain.PostSort fields, creation time when
+	// empty. Posts come in descending order unless Ascending is set and
+	// ties are broken by id. With a key set the listing starts right
+	// after the (sort value, id) key, or ends right before it when
+	// Backward is set.
+	Sort      string
+	Ascending bool
+	KeyValue  interface{} `db:"k_value"`
+	KeyID     int         `db:"k_id"`
+	Backward  bool
+	Limit     uint64
+	Offset    uint64
+}
+
+var postSortColumns = map[string]string{
+	domain.PostSortCreated:   "p.created_at",
+	domain.PostSortPublished: "p.published_at",
+	domain.PostSortUpdated:   "p.updated_at",
+	domain.PostSortTitle:     "p.title",
+	domain.PostSortPopular:   "p.views",
+	domain.PostSortScheduled: "p.publish_at",
 }
 
 type CreatePost struct {
-	UserID      int
-	ReadingTime int
-	Status      int
-	Title       string
-	Subtitle    string
-	ImageURL    string
-	Content     string
-	Slug        string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	UserID        int
+	ReadingTime   int
+	WordCount     int
+	Excerpt       string
+	ManualReading bool
+	ManualExcerpt bool
+	Status        int
+	Title         string
+	Subtitle      string
+	ImageURL      string
+	Content       string
+	Format        string
+	ContentHTML   string
+	RenderVersion int
+	Slug          string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UpdatedBy     int
+	SearchConfig  string
+	TagIDs        []int
 }
 
 type UpdatePost struct {
-	ID          int
-	ReadingTime int
-	Status      int
-	Title       string
-	Subtitle    string
-	ImageURL    string
-	Content     string
-	Slug        string
-	PublishedAt sql.NullTime
-	UpdatedAt   time.Time
+	ID            int
+	ReadingTime   int
+	WordCount     int
+	Excerpt       string
+	ManualReading bool
+	ManualExcerpt bool
+	Status        int
+	Title         string
+	Subtitle      string
+	ImageURL      string
+	Content       string
+	Format        string
+	ContentHTML   string
+	RenderVersion int
+	Slug          string
+	PublishedAt   sql.NullTime
+	PublishAt     sql.NullTime
+	UpdatedAt     time.Time
+	UpdatedBy     int
+	// PreviousSlug keeps redirecting to the post when the slug changes.
+	PreviousSlug string
+	TagIDs       []int
+}
+
+type PostRendering struct {
+	ID            int
+	ContentHTML   string
+	RenderVersion int
+	ReadingTime   int
+	WordCount     int
+	Excerpt       string
+}
+
+type PostSchedule struct {
+	ID        int
+	Status    int
+	PublishAt sql.NullTime
+	UpdatedAt time.Time
 }
 
 type DeletePost struct {
@@ -107,124 +209,286 @@ func NewPostsRepo(db *sqlx.DB, log logger.Logger) *PostsRepo {
 }
 
 func (r *PostsRepo) GetPostsByCriteria(ctx context.Context, criteria PostCriteria) ([]*Post, error) {
-	sb := squirrel.Select(`p.id`).
-		From(postsTable + " p").
-		LeftJoin(postsTagsTable + " pt ON p.id = pt.post_id").
-		LeftJoin(tagsTable + " t ON pt.tag_id = t.id")
-
-	if criteria.UserID > 0 {
-		sb = sb.Where("p.user_id = :p_user_id", criteria.UserID)
+	if criteria.Limit == 0 {
+		criteria.Limit = postsPerPage
 	}
-	if criteria.Status != domain.PostStatusDeleted {
-		sb = sb.Where(fmt.Sprintf("p.status <> %d", domain.PostStatusDeleted))
+	rows, err := r.db.NamedQueryContext(ctx, postsQuery(criteria), criteria)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
 	}
-	if criteria.Status > 0 {
-		sb = sb.Where("p.status = :p_status", criteria.Status)
+	if err != nil {
+		return nil, err
 	}
-	if criteria.ID > 0 {
-		sb = sb.Where("p.id = :p_id", criteria.ID)
+	defer func() { _ = rows.Close() }()
+
+	out, err := scanPostRows(rows)
+	if err == sql.ErrNoRows || len(out) == 0 {
+		return nil, ErrNotFound
 	}
-	if criteria.TagID > 0 {
-		sb = sb.Where("t.id = :t_id", criteria.TagID)
+	return out, nil
+}
+
+// postsQuery builds the named query listing the posts matching the
+// criteria in their sort order.
+func postsQuery(criteria PostCriteria) string {
+	column, ok := postSortColumns[criteria.Sort]
+	if !ok {
+		column = postSortColumns[domain.PostSortCreated]
 	}
-	if criteria.Limit == 0 {
-		criteria.Limit = postsPerPage
+	order := sortOrder(column, !criteria.Ascending)
+	// A backward page is picked walking away from the key and put back in
+	// listing order by the outer query.
+	inner, cmp := order, "<"
+	if criteria.Ascending != criteria.Backward {
+		cmp = ">"
+	}
+	if criteria.Backward {
+		inner = sortOrder(column, criteria.Ascending)
 	}
 
-	sb.GroupBy("p.id").Limit(criteria.Limit).Offset(criteria.Offset)
+	sb := filterPosts(squirrel.Select(`p.id`), criteria).GroupBy("p.id").OrderBy(inner)
+	if criteria.KeyID > 0 {
+		sb = sb.Where(fmt.Sprintf("(%s, p.id) %s (:k_value, :k_id)", column, cmp))
+	}
+	sb = sb.Limit(criteria.Limit).Offset(criteria.Offset)
 
-	query, _, _ := squirrel.Select(`
-			p.id AS p_id, 
+	query, _, _ := squirrel.Select(`			p.id AS p_id, 
 			p.user_id AS p_user_id, 
 			p.reading_time AS p_reading_time, 
+			p.word_count AS p_word_count,
+			p.excerpt AS p_excerpt,
+			p.reading_time_manual AS p_reading_time_manual,
+			p.excerpt_manual AS p_excerpt_manual,
 			p.status AS p_status,
 			p.title AS p_title, 
 			p.subtitle AS p_subtitle, 
 			p.image_url AS p_image_url, 
 			p.content AS p_content, 
+			p.format AS p_format,
+			p.content_html AS p_content_html,
+			p.render_version AS p_render_version,
 			p.slug AS p_slug, 
+			p.views AS p_views,
 			p.published_at AS p_published_at, 
+			p.publish_at AS p_publish_at,
 			p.created_at AS p_created_at, 
 			p.updated_at AS p_updated_at, 
 			p.deleted_at AS p_deleted_at,
+			u.username AS u_username,
+			u.display_name AS u_display_name,
+			u.avatar_url AS u_avatar_url,
 			t.id AS t_id, 
 			t.name AS t_name, 
 			t.slug AS t_slug`).
 		From(postsTable + " p").
+		LeftJoin(usersTable + " u ON p.user_id = u.id").
 		LeftJoin(postsTagsTable + " pt ON p.id = pt.post_id").
 		LeftJoin(tagsTable + " t ON pt.tag_id = t.id").
 		Where(subquery("p.id IN", sb)).
-		OrderBy("p.created_at DESC").
+		OrderBy(order).
 		ToSql()
+	return query
+}
 
+// filterPosts adds the joins and conditions selecting the posts that
+// match the criteria.
+func filterPosts(sb squirrel.SelectBuilder, criteria PostCriteria) squirrel.SelectBuilder {
+	sb = sb.From(postsTable + " p").
+		LeftJoin(postsTagsTable + " pt ON p.id = pt.post_id").
+		LeftJoin(tagsTable + " t ON pt.tag_id = t.id")
+
+	if criteria.UserID > 0 {
+		sb = sb.Where("p.user_id = :p_user_id", criteria.UserID)
+	}
+	if criteria.Status != domain.PostStatusDeleted && !criteria.IncludeDeleted {
+		sb = sb.Where(fmt.Sprintf("p.status <> %d", domain.PostStatusDeleted))
+	}
+	if criteria.Status > 0 {
+		sb = sb.Where("p.status = :p_status", criteria.Status)
+	}
+	if criteria.ID > 0 {
+		sb = sb.Where("p.id = :p_id", criteria.ID)
+	}
+	if criteria.TagID > 0 {
+		sb = sb.Where("t.id = :t_id", criteria.TagID)
+	}
+	if criteria.Slug != "" {
+		sb = sb.Where("p.slug = :p_slug", criteria.Slug)
+	}
+	if len(criteria.IDs) > 0 {
+		sb = sb.Where("p.id = ANY(:p_ids)")
+	}
+	if len(criteria.TagSlugs) > 0 && criteria.AllTags {
+		sb = sb.Where(fmt.Sprintf(`p.id IN (
+			SELECT fpt.post_id FROM %s fpt JOIN %s ft ON fpt.tag_id = ft.id
+			WHERE ft.slug = ANY(:t_slugs)
+			GROUP BY fpt.post_id HAVING COUNT(DISTINCT ft.id) = %d)`,
+			postsTagsTable, tagsTable, len(criteria.TagSlugs)))
+	} else if len(criteria.TagSlugs) > 0 {
+		sb = sb.Where("t.slug = ANY(:t_slugs)")
+	}
+	if criteria.Username != "" {
+		sb = sb.Where(fmt.Sprintf("p.user_id IN (SELECT id FROM %s WHERE username = :u_username)", usersTable))
+	}
+	if !criteria.PublishedFrom.IsZero() {
+		sb = sb.Where("p.published_at >= :p_published_from")
+	}
+	if !criteria.PublishedTo.IsZero() {
+		sb = sb.Where("p.published_at < :p_published_to")
+	}
+	if criteria.HasImage != nil && *criteria.HasImage {
+		sb = sb.Where("COALESCE(p.image_url, '') <> ''")
+	} else if criteria.HasImage != nil {
+		sb = sb.Where("COALESCE(p.image_url, '') = ''")
+	}
+	return sb
+}
+
+// CountPostsByCriteria counts all posts matching the criteria, ignoring
+// the key and the limits.
+func (r *PostsRepo) CountPostsByCriteria(ctx context.Context, criteria PostCriteria) (int, error) {
+	query, _, _ := filterPosts(squirrel.Select("COUNT(DISTINCT p.id)"), criteria).ToSql()
 	rows, err := r.db.NamedQueryContext(ctx, query, criteria)
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
+	if err != nil {
+		return 0, err
 	}
+	defer func() { _ = rows.Close() }()
+
+	var count int
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
+}
+
+// CountPostsByStatus counts the posts matching the criteria, whatever
+// their status, for each status.
+func (r *PostsRepo) CountPostsByStatus(ctx context.Context, criteria PostCriteria) (map[int]int, error) {
+	criteria.Status, criteria.IncludeDeleted = 0, true
+	query, _, _ := filterPosts(squirrel.Select("p.status", "COUNT(DISTINCT p.id)"), criteria).
+		GroupBy("p.status").
+		ToSql()
+	rows, err := r.db.NamedQueryContext(ctx, query, criteria)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	out, err := scanPostRows(rows)
-	if err == sql.ErrNoRows || len(out) == 0 {
-		return nil, ErrNotFound
+	counts := make(map[int]int)
+	for rows.Next() {
+		var status, count int
+		if err = rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
 	}
-	return out, nil
+	return counts, rows.Err()
 }
 
+// CreatePost stores the post along with its tags. A slug that is already
+// taken yields ErrSlugTaken.
 func (r *PostsRepo) CreatePost(ctx context.Context, createPost CreatePost) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	var id int
 	query, args, _ := squirrel.Insert(postsTable).
 		SetMap(map[string]interface{}{
-			"user_id":      createPost.UserID,
-			"reading_time": createPost.ReadingTime,
-			"status":       createPost.Status,
-			"title":        createPost.Title,
-			"subtitle":     createPost.Subtitle,
-			"image_url":    createPost.ImageURL,
-			"content":      createPost.Content,
-			"slug":         createPost.Slug,
-			"created_at":   createPost.CreatedAt,
-			"updated_at":   createPost.UpdatedAt,
+			"user_id":             createPost.UserID,
+			"reading_time":        createPost.ReadingTime,
+			"word_count":          createPost.WordCount,
+			"excerpt":             createPost.Excerpt,
+			"reading_time_manual": createPost.ManualReading,
+			"excerpt_manual":      createPost.ManualExcerpt,
+			"status":              createPost.Status,
+			"title":               createPost.Title,
+			"subtitle":            createPost.Subtitle,
+			"image_url":           createPost.ImageURL,
+			"content":             createPost.Content,
+			"format":              createPost.Format,
+			"content_html":        createPost.ContentHTML,
+			"render_version":      createPost.RenderVersion,
+			"slug":                createPost.Slug,
+			"created_at":          createPost.CreatedAt,
+			"updated_at":          createPost.UpdatedAt,
+			"updated_by":          createPost.UpdatedBy,
+			"search_config":       createPost.SearchConfig,
 		}).
 		Suffix("RETURNING \"id\"").
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	err = tx.QueryRowxContext(ctx, query, args...).Scan(&id)
+	if isUniqueViolation(err, postsSlugKey) {
+		return 0, ErrSlugTaken
+	}
 	if err != nil {
 		return 0, err
 	}
-	defer func() { _ = rows.Close() }()
-	if rows.Next() {
-		if err = rows.Err(); err != nil {
-			return 0, err
-		}
-		if err = rows.Scan(&id); err != nil {
-			return 0, err
-		}
+	if err := moveSlug(ctx, tx, domain.RedirectKindPost, id, "", createPost.Slug, createPost.CreatedAt); err != nil {
+		return 0, err
+	}
+	if err := setPostTags(ctx, tx, id, createPost.TagIDs); err != nil {
+		return 0, err
 	}
-	return id, nil
+	return id, tx.Commit()
 }
 
+// UpdatePost keeps the previous state of the post as a revision before
+// overwriting it, along with its tags. A slug that is already taken yields
+// ErrSlugTaken.
 func (r *PostsRepo) UpdatePost(ctx context.Context, updatePost UpdatePost) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := snapshotPost(ctx, tx, updatePost.ID, updatePost.UpdatedAt); err != nil {
+		return err
+	}
 	query, args, _ := squirrel.Update(postsTable).
 		SetMap(map[string]interface{}{
-			"reading_time": updatePost.ReadingTime,
-			"status":       updatePost.Status,
-			"title":        updatePost.Title,
-			"subtitle":     updatePost.Subtitle,
-			"image_url":    updatePost.ImageURL,
-			"content":      updatePost.Content,
-			"slug":         updatePost.Slug,
-			"published_at": updatePost.PublishedAt,
-			"updated_at":   updatePost.UpdatedAt,
+			"reading_time":        updatePost.ReadingTime,
+			"word_count":          updatePost.WordCount,
+			"excerpt":             updatePost.Excerpt,
+			"reading_time_manual": updatePost.ManualReading,
+			"excerpt_manual":      updatePost.ManualExcerpt,
+			"status":              updatePost.Status,
+			"title":               updatePost.Title,
+			"subtitle":            updatePost.Subtitle,
+			"image_url":           updatePost.ImageURL,
+			"content":             updatePost.Content,
+			"format":              updatePost.Format,
+			"content_html":        updatePost.ContentHTML,
+			"render_version":      updatePost.RenderVersion,
+			"slug":                updatePost.Slug,
+			"published_at":        updatePost.PublishedAt,
+			"publish_at":          updatePost.PublishAt,
+			"updated_at":          updatePost.UpdatedAt,
+			"updated_by":          updatePost.UpdatedBy,
 		}).
 		Where("id = ?", updatePost.ID).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+	_, err = tx.ExecContext(ctx, query, args...)
+	if isUniqueViolation(err, postsSlugKey) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	if err := moveSlug(ctx, tx, domain.RedirectKindPost, updatePost.ID, updatePost.PreviousSlug, updatePost.Slug, updatePost.UpdatedAt); err != nil {
+		return err
+	}
+	if err := setPostTags(ctx, tx, updatePost.ID, updatePost.TagIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *PostsRepo) DeletePost(ctx context.Context, deletePost DeletePost) error {
@@ -240,32 +504,216 @@ func (r *PostsRepo) DeletePost(ctx context.Context, deletePost DeletePost) error
 	return err
 }
 
+// UpdatePostRendering stores output derived from the post content.
+func (r *PostsRepo) UpdatePostRendering(ctx context.Context, rendering PostRendering) error {
+	query, args, _ := squirrel.Update(postsTable).
+		SetMap(map[string]interface{}{
+			"content_html":   rendering.ContentHTML,
+			"render_version": rendering.RenderVersion,
+			"reading_time":   rendering.ReadingTime,
+			"word_count":     rendering.WordCount,
+			"excerpt":        rendering.Excerpt,
+		}).
+		Where("id = ?", rendering.ID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// IncrementPostViews counts one more view of the post.
+func (r *PostsRepo) IncrementPostViews(ctx context.Context, id int) error {
+	query, args, _ := squirrel.Update(postsTable).
+		Set("views", squirrel.Expr("views + 1")).
+		Where("id = ?", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+type PostSearch struct {
+	// Query is in tsquery syntax and parsed with the Language configuration.
+	Query    string
+	Language string
+	AuthorID int
+	TagSlug  string
+	// ViewerID sees their own posts whatever the status, AllStatuses lets
+	// moderators see every post that is not deleted. Everybody else only
+	// finds published posts.
+	ViewerID    int
+	AllStatuses bool
+	Limit       uint64
+	Offset      uint64
+}
+
+type PostSearchResult struct {
+	ID      int     `db:"id"`
+	Rank    float64 `db:"rank"`
+	Snippet string  `db:"snippet"`
+}
+
+// SearchPosts ranks the posts matching a full-text query and returns their
+// ids with a highlighted snippet of the content. Snippets are HTML; only
+// the <mark> tags around matches are added to the escaped text.
+func (r *PostsRepo) SearchPosts(ctx context.Context, search PostSearch) ([]*PostSearchResult, error) {
+	sb := squirrel.Select(`
+			p.id,
+			ts_rank_cd(p.search_vector, q) AS rank,
+			ts_headline(p.search_config, regexp_replace(p.content_html, '<[^>]*>', ' ', 'g'), q,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS snippet`).
+		From(postsTable+" p").
+		CrossJoin("to_tsquery(?::regconfig, ?) q", search.Language, search.Query).
+		Where("p.search_vector @@ q").
+		Where(fmt.Sprintf("p.status <> %d", domain.PostStatusDeleted))
+
+	switch {
+	case search.AllStatuses:
+	case search.ViewerID > 0:
+		sb = sb.Where(squirrel.Or{
+			squirrel.Eq{"p.status": domain.PostStatusPublished},
+			squirrel.Eq{"p.user_id": search.ViewerID},
+		})
+	default:
+		sb = sb.Where(squirrel.Eq{"p.status": domain.PostStatusPublished})
+	}
+	if search.AuthorID > 0 {
+		sb = sb.Where(squirrel.Eq{"p.user_id": search.AuthorID})
+	}
+	if search.TagSlug != "" {
+		sb = sb.Where(fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM %s pt JOIN %s t ON t.id = pt.tag_id
+			WHERE pt.post_id = p.id AND t.slug = ?)`, postsTagsTable, tagsTable), search.TagSlug)
+	}
+	if search.Limit == 0 {
+		search.Limit = postsPerPage
+	}
+
+	query, args, _ := sb.OrderBy("rank DESC", "p.id DESC").
+		Limit(search.Limit).
+		Offset(search.Offset).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	out := make([]*PostSearchResult, 0)
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostsRepo) UpdatePostSchedule(ctx context.Context, schedule PostSchedule) error {
+	query, args, _ := squirrel.Update(postsTable).
+		SetMap(map[string]interface{}{
+			"status":     schedule.Status,
+			"publish_at": schedule.PublishAt,
+			"updated_at": schedule.UpdatedAt,
+		}).
+		Where("id = ?", schedule.ID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// PublishDuePosts publishes up to limit scheduled posts whose time has come
+// and returns their ids. Rows locked by another instance doing the same are
+// skipped, so each post is published exactly once.
+func (r *PostsRepo) PublishDuePosts(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	query := fmt.Sprintf(`
+		WITH due AS (
+			SELECT id FROM %[1]s
+			WHERE status = %[2]d AND publish_at <= $1
+			ORDER BY publish_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE %[1]s p
+		SET status = %[3]d, published_at = p.publish_at, publish_at = NULL, updated_at = $1
+		FROM due
+		WHERE p.id = due.id
+		RETURNING p.id`, postsTable, domain.PostStatusScheduled, domain.PostStatusPublished)
+	ids := make([]int, 0)
+	if err := r.db.SelectContext(ctx, &ids, query, now, limit); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetSlugsLike lists post slugs equal to base or made of base and a suffix.
+func (r *PostsRepo) GetSlugsLike(ctx context.Context, base string, excludeID int) ([]string, error) {
+	return getSlugsLike(ctx, r.db, postsTable, base, excludeID)
+}
+
+func getSlugsLike(ctx context.Context, db *sqlx.DB, table, base string, excludeID int) ([]string, error) {
+	query, args, _ := squirrel.Select("slug").
+		From(table).
+		Where(squirrel.Or{squirrel.Eq{"slug": base}, squirrel.Like{"slug": base + "-%"}}).
+		Where(squirrel.NotEq{"id": excludeID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	slugs := make([]string, 0)
+	if err := db.SelectContext(ctx, &slugs, query, args...); err != nil {
+		return nil, err
+	}
+	return slugs, nil
+}
+
 func scanPostRows(rows *sqlx.Rows) ([]*Post, error) {
-	posts := make(map[int]*Post)
-	out := make([]*Post, 0)
+	pts := make([]*PostTag, 0)
 	for rows.Next() {
 		pt := &PostTag{}
 		if err := rows.StructScan(pt); err != nil {
 			return nil, err
 		}
+		pts = append(pts, pt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groupPostTags(pts), nil
+}
+
+// groupPostTags folds the rows of a post and tag join into posts, keeping
+// the order in which the posts first appear.
+func groupPostTags(pts []*PostTag) []*Post {
+	posts := make(map[int]*Post)
+	out := make([]*Post, 0)
+	for _, pt := range pts {
 		if _, ok := posts[pt.ID]; !ok {
 			p := &Post{
-				ID:          pt.ID,
-				UserID:      pt.UserID,
-				ReadingTime: pt.ReadingTime,
-				Status:      pt.Status,
-				Title:       pt.Title,
-				Subtitle:    pt.Subtitle,
-				ImageURL:    pt.ImageURL,
-				Content:     pt.Content,
-				Slug:        pt.Slug,
-				PublishedAt: pt.PublishedAt,
-				CreatedAt:   pt.CreatedAt,
-				UpdatedAt:   pt.UpdatedAt,
-				DeletedAt:   pt.DeletedAt,
+				ID:            pt.ID,
+				UserID:        pt.UserID,
+				ReadingTime:   pt.ReadingTime,
+				WordCount:     pt.WordCount,
+				Excerpt:       pt.Excerpt,
+				ManualReading: pt.ManualReading,
+				ManualExcerpt: pt.ManualExcerpt,
+				Status:        pt.Status,
+				Title:         pt.Title,
+				Subtitle:      pt.Subtitle,
+				ImageURL:      pt.ImageURL,
+				Content:       pt.Content,
+				Format:        pt.Format,
+				ContentHTML:   pt.ContentHTML,
+				RenderVersion: pt.RenderVersion,
+				Slug:          pt.Slug,
+				Views:         pt.Views,
+				PublishedAt:   pt.PublishedAt,
+				PublishAt:     pt.PublishAt,
+				CreatedAt:     pt.CreatedAt,
+				UpdatedAt:     pt.UpdatedAt,
+				DeletedAt:     pt.DeletedAt,
+			}
+			if pt.Username.Valid {
+				p.Author = &PostAuthor{
+					Username:    pt.Username.String,
+					DisplayName: pt.DisplayName.String,
+					AvatarURL:   pt.AvatarURL.String,
+				}
 			}
 			p.Tags = make([]*Tag, 0)
 			posts[p.ID] = p
+			out = append(out, p)
 		}
 		if pt.TagID.Valid && pt.TagName.Valid && pt.TagSlug.Valid {
 			t := &Tag{
@@ -276,10 +724,18 @@ func scanPostRows(rows *sqlx.Rows) ([]*Post, error) {
 			posts[pt.ID].Tags = append(posts[pt.ID].Tags, t)
 		}
 	}
-	for _, p := range posts {
-		out = append(out, p)
+	return out
+}
+
+// sortOrder orders by the column and then by id between equal values.
+// Posts without a value go last in descending order and first in
+// ascending order, so that flipping the direction walks the same list
+// backwards.
+func sortOrder(column string, desc bool) string {
+	if desc {
+		return fmt.Sprintf("%s DESC NULLS LAST, p.id DESC", column)
 	}
-	return out, nil
+	return fmt.Sprintf("%s ASC NULLS FIRST, p.id ASC", column)
 }
 
 func subquery(prefix string, sb squirrel.SelectBuilder) squirrel.Sqlizer {
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/scraletteykt/my-blog/internal/domain"
+)
+
+func TestGroupPostTagsKeepsRowOrder(t *testing.T) {
+	tag := func(id int32, slug string) (sql.NullInt32, sql.NullString, sql.NullString) {
+		return sql.NullInt32{Int32: id, Valid: true}, sql.NullString{String: slug, Valid: true}, sql.NullString{String: slug, Valid: true}
+	}
+	row := func(postID int, tagID int32, tagSlug string) *PostTag {
+		pt := &PostTag{ID: postID, Title: "post"}
+		if tagID > 0 {
+			pt.TagID, pt.TagName, pt.TagSlug = tag(tagID, tagSlug)
+		}
+		return pt
+	}
+
+	// Rows come sorted by the query, a post with several tags spanning
+	// consecutive rows.
+	rows := []*PostTag{
+		row(50, 1, "go"),
+		row(50, 2, "sql"),
+		row(7, 0, ""),
+		row(31, 2, "sql"),
+	}
+	for id := 100; id > 60; id-- {
+		rows = append(rows, row(id, 0, ""))
+	}
+
+	posts := groupPostTags(rows)
+	want := []int{50, 7, 31}
+	for id := 100; id > 60; id-- {
+		want = append(want, id)
+	}
+	if len(posts) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(posts), len(want))
+	}
+	for i, p := range posts {
+		if p.ID != want[i] {
+			t.Fatalf("post %d: got id %d, want %d", i, p.ID, want[i])
+		}
+	}
+	if got := len(posts[0].Tags); got != 2 || posts[0].Tags[0].Slug != "go" || posts[0].Tags[1].Slug != "sql" {
+		t.Errorf("post 50: got %d tags, want go and sql in row order", got)
+	}
+	if got := len(posts[1].Tags); got != 0 {
+		t.Errorf("post 7: got %d tags, want none", got)
+	}
+}
+
+func TestPostsQueryOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		criteria  PostCriteria
+		inner     string
+		outer     string
+		keyFilter string
+	}{
+		{
+			name:     "default is creation time",
+			criteria: PostCriteria{},
+			inner:    "ORDER BY p.created_at DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.created_at DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "unknown sort falls back to creation time",
+			criteria: PostCriteria{Sort: "p.id; DROP TABLE posts"},
+			inner:    "ORDER BY p.created_at DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.created_at DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "published descending",
+			criteria: PostCriteria{Sort: domain.PostSortPublished},
+			inner:    "ORDER BY p.published_at DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.published_at DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "published ascending",
+			criteria: PostCriteria{Sort: domain.PostSortPublished, Ascending: true},
+			inner:    "ORDER BY p.published_at ASC NULLS FIRST, p.id ASC",
+			outer:    "ORDER BY p.published_at ASC NULLS FIRST, p.id ASC",
+		},
+		{
+			name:     "updated descending",
+			criteria: PostCriteria{Sort: domain.PostSortUpdated},
+			inner:    "ORDER BY p.updated_at DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.updated_at DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "updated ascending",
+			criteria: PostCriteria{Sort: domain.PostSortUpdated, Ascending: true},
+			inner:    "ORDER BY p.updated_at ASC NULLS FIRST, p.id ASC",
+			outer:    "ORDER BY p.updated_at ASC NULLS FIRST, p.id ASC",
+		},
+		{
+			name:     "title ascending",
+			criteria: PostCriteria{Sort: domain.PostSortTitle, Ascending: true},
+			inner:    "ORDER BY p.title ASC NULLS FIRST, p.id ASC",
+			outer:    "ORDER BY p.title ASC NULLS FIRST, p.id ASC",
+		},
+		{
+			name:     "title descending",
+			criteria: PostCriteria{Sort: domain.PostSortTitle},
+			inner:    "ORDER BY p.title DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.title DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "popular descending",
+			criteria: PostCriteria{Sort: domain.PostSortPopular},
+			inner:    "ORDER BY p.views DESC NULLS LAST, p.id DESC",
+			outer:    "ORDER BY p.views DESC NULLS LAST, p.id DESC",
+		},
+		{
+			name:     "popular ascending",
+			criteria: PostCriteria{Sort: domain.PostSortPopular, Ascending: true},
+			inner:    "ORDER BY p.views ASC NULLS FIRST, p.id ASC",
+			outer:    "ORDER BY p.views ASC NULLS FIRST, p.id ASC",
+		},
+		{
+			name:     "scheduled soonest first",
+			criteria: PostCriteria{Sort: domain.PostSortScheduled, Ascending: true},
+			inner:    "ORDER BY p.publish_at ASC NULLS FIRST, p.id ASC",
+			outer:    "ORDER BY p.publish_at ASC NULLS FIRST, p.id ASC",
+		},
+		{
+			name:      "next page descending",
+			criteria:  PostCriteria{Sort: domain.PostSortPublished, KeyID: 4},
+			inner:     "ORDER BY p.published_at DESC NULLS LAST, p.id DESC",
+			outer:     "ORDER BY p.published_at DESC NULLS LAST, p.id DESC",
+			keyFilter: "(p.published_at, p.id) < (:k_value, :k_id)",
+		},
+		{
+			name:      "previous page descending",
+			criteria:  PostCriteria{Sort: domain.PostSortPublished, KeyID: 4, Backward: true},
+			inner:     "ORDER BY p.published_at ASC NULLS FIRST, p.id ASC",
+			outer:     "ORDER BY p.published_at DESC NULLS LAST, p.id DESC",
+			keyFilter: "(p.published_at, p.id) > (:k_value, :k_id)",
+		},
+		{
+			name:      "next page ascending",
+			criteria:  PostCriteria{Sort: domain.PostSortTitle, Ascending: true, KeyID: 4},
+			inner:     "ORDER BY p.title ASC NULLS FIRST, p.id ASC",
+			outer:     "ORDER BY p.title ASC NULLS FIRST, p.id ASC",
+			keyFilter: "(p.title, p.id) > (:k_value, :k_id)",
+		},
+		{
+			name:      "previous page ascending",
+			criteria:  PostCriteria{Sort: domain.PostSortTitle, Ascending: true, KeyID: 4, Backward: true},
+			inner:     "ORDER BY p.title DESC NULLS LAST, p.id DESC",
+			outer:     "ORDER BY p.title ASC NULLS FIRST, p.id ASC",
+			keyFilter: "(p.title, p.id) < (:k_value, :k_id)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.criteria.Limit = postsPerPage
+			query := postsQuery(tt.criteria)
+			start := strings.Index(query, "p.id IN (")
+			end := strings.LastIndex(query, ")")
+			if start < 0 || end < start {
+				t.Fatalf("no id subquery in %q", query)
+			}
+			sub, outer := query[start:end], query[end:]
+
+			if !strings.Contains(sub, tt.inner+" LIMIT") {
+				t.Errorf("subquery %q does not order by %q before its limit", sub, tt.inner)
+			}
+			if !strings.HasSuffix(outer, tt.outer) {
+				t.Errorf("query ends with %q, want %q", outer, tt.outer)
+			}
+			if tt.keyFilter == "" && strings.Contains(sub, ":k_id") {
+				t.Errorf("subquery %q filters on a key that isn't set", sub)
+			}
+			if tt.keyFilter != "" && !strings.Contains(sub, tt.keyFilter) {
+				t.Errorf("subquery %q does not filter on %q", sub, tt.keyFilter)
+			}
+		})
+	}
+}
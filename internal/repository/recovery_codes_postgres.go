@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const recoveryCodesTable = "recovery_codes"
+
+type RecoveryCodesRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewRecoveryCodesRepo(db *sqlx.DB, log logger.Logger) *RecoveryCodesRepo {
+	return &RecoveryCodesRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *RecoveryCodesRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string, createdAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query, args, _ := squirrel.Delete(recoveryCodesTable).
+		Where("user_id = ?", userID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if len(codeHashes) > 0 {
+		ib := squirrel.Insert(recoveryCodesTable).
+			Columns("user_id", "code_hash", "created_at").
+			PlaceholderFormat(squirrel.Dollar)
+		for _, h := range codeHashes {
+			ib = ib.Values(userID, h, createdAt)
+		}
+		query, args, _ = ib.ToSql()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *RecoveryCodesRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error) {
+	query, args, _ := squirrel.Update(recoveryCodesTable).
+		Set("used_at", usedAt).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
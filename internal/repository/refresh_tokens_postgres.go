@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const refreshTokensTable = "refresh_tokens"
+
+type RefreshToken struct {
+	ID        int          `db:"id"`
+	UserID    int          `db:"user_id"`
+	SessionID int          `db:"session_id"`
+	Family    string       `db:"family"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	CreatedAt time.Time    `db:"created_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+}
+
+type CreateRefreshToken struct {
+	UserID    int
+	SessionID int
+	Family    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokensRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewRefreshTokensRepo(db *sqlx.DB, log logger.Logger) *RefreshTokensRepo {
+	return &RefreshTokensRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *RefreshTokensRepo) CreateRefreshToken(ctx context.Context, createToken CreateRefreshToken) (int, error) {
+	var id int
+	query, args, _ := squirrel.Insert(refreshTokensTable).
+		SetMap(map[string]interface{}{
+			"user_id":    createToken.UserID,
+			"session_id": createToken.SessionID,
+			"family":     createToken.Family,
+			"token_hash": createToken.TokenHash,
+			"expires_at": createToken.ExpiresAt,
+			"created_at": createToken.CreatedAt,
+		}).
+		Suffix("RETURNING \"id\"").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *RefreshTokensRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var t RefreshToken
+	query, args, _ := squirrel.Select("id, user_id, session_id, family, token_hash, expires_at, created_at, used_at, revoked_at").
+		From(refreshTokensTable).
+		Where("token_hash = ?", tokenHash).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.GetContext(ctx, &t, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// UseRefreshToken marks the token as used and reports whether this call was
+// the one that did it, so two concurrent refreshes cannot both succeed.
+func (r *RefreshTokensRepo) UseRefreshToken(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	query, args, _ := squirrel.Update(refreshTokensTable).
+		Set("used_at", usedAt).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *RefreshTokensRepo) RevokeRefreshTokenFamily(ctx context.Context, family string, revokedAt time.Time) error {
+	query, args, _ := squirrel.Update(refreshTokensTable).
+		Set("revoked_at", revokedAt).
+		Where("family = ? AND revoked_at IS NULL", family).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
@@ -2,53 +2,172 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/scraletteykt/my-blog/internal/domain"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
 )
 
-var ErrNotFound = errors.New("not found rows in result set")
+var (
+	ErrNotFound  = errors.New("not found rows in result set")
+	ErrSlugTaken = errors.New("slug is already in use")
+	ErrNameTaken = errors.New("name is already in use")
+)
+
+// isUniqueViolation reports whether err was raised by the given unique
+// index or constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
 
 type Users interface {
 	CreateUser(ctx context.Context, user domain.User) (int, error)
 	GetUser(ctx context.Context, username string) (*domain.User, error)
 	GetUserByID(ctx context.Context, userID int) (*domain.User, error)
+	GetUsers(ctx context.Context) ([]*domain.User, error)
+	UpdateUserRole(ctx context.Context, id int, role string) error
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	SetEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error
+	UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error
+	SetTOTPSecret(ctx context.Context, id int, secret string) error
+	SetTOTPEnabled(ctx context.Context, id int, enabled bool) error
+	UseTOTPStep(ctx context.Context, id int, step int64) (bool, error)
+	GetProfile(ctx context.Context, username string) (*Profile, error)
+	GetProfileByID(ctx context.Context, id int) (*Profile, error)
+	UpdateProfile(ctx context.Context, updateProfile UpdateProfile) error
+	SetDeletionRequested(ctx context.Context, id int, requestedAt sql.NullTime) error
+	GetDeletionRequests(ctx context.Context) ([]*DeletionRequest, error)
+	AnonymizeUser(ctx context.Context, id int, deletedAt time.Time, loginFailureKeys []string) error
+	TransferAndDeleteUser(ctx context.Context, id, toID int, loginFailureKeys []string) error
 }
 
 type Posts interface {
 	GetPostsByCriteria(ctx context.Context, criteria PostCriteria) ([]*Post, error)
+	CountPostsByCriteria(ctx context.Context, criteria PostCriteria) (int, error)
+	CountPostsByStatus(ctx context.Context, criteria PostCriteria) (map[int]int, error)
+	IncrementPostViews(ctx context.Context, id int) error
 	CreatePost(ctx context.Context, createPost CreatePost) (int, error)
 	UpdatePost(ctx context.Context, updatePost UpdatePost) error
 	DeletePost(ctx context.Context, deletePost DeletePost) error
+	UpdatePostRendering(ctx context.Context, rendering PostRendering) error
+	GetSlugsLike(ctx context.Context, base string, excludeID int) ([]string, error)
+	UpdatePostSchedule(ctx context.Context, schedule PostSchedule) error
+	PublishDuePosts(ctx context.Context, now time.Time, limit int) ([]int, error)
+	SearchPosts(ctx context.Context, search PostSearch) ([]*PostSearchResult, error)
 }
 
 type Tags interface {
 	GetTagByID(ctx context.Context, id int) (*Tag, error)
+	GetTagBySlug(ctx context.Context, slug string) (*Tag, error)
+	GetSlugsLike(ctx context.Context, base string, excludeID int) ([]string, error)
 	GetTags(ctx context.Context) ([]*Tag, error)
 	CreateTag(ctx context.Context, createTag CreateTag) (int, error)
 	UpdateTag(ctx context.Context, updateTag UpdateTag) error
 	DeleteTag(ctx context.Context, deleteTag DeleteTag) error
 }
 
-type PostsTags interface {
-	TagPost(ctx context.Context, tagID, postID int) error
-	UntagPost(ctx context.Context, tagID, postID int) error
-	UpdatePostTags(ctx context.Context, tagIDs []int, postID int) error
+type PostRevisions interface {
+	GetRevisions(ctx context.Context, postID int) ([]*PostRevision, error)
+	GetRevision(ctx context.Context, postID, id int) (*PostRevision, error)
+}
+
+type SlugRedirects interface {
+	GetSlugRedirect(ctx context.Context, kind, slug string) (*SlugRedirect, error)
+	GetSlugRedirects(ctx context.Context, kind string) ([]*SlugRedirect, error)
+	DeleteSlugRedirect(ctx context.Context, id int) error
+	DeleteSlugRedirectsByTarget(ctx context.Context, kind string, targetID int) error
+}
+
+type RefreshTokens interface {
+	CreateRefreshToken(ctx context.Context, createToken CreateRefreshToken) (int, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id int, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, family string, revokedAt time.Time) error
+}
+
+type Sessions interface {
+	CreateSession(ctx context.Context, createSession CreateSession) (int, error)
+	GetSessionByHash(ctx context.Context, tokenHash string) (*Session, error)
+	GetSessionByID(ctx context.Context, id int) (*Session, error)
+	GetActiveSessionsByUser(ctx context.Context, userID int, now time.Time) ([]*Session, error)
+	TouchSession(ctx context.Context, id int, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID, exceptID int, revokedAt time.Time) error
+}
+
+type UserTokens interface {
+	CreateUserToken(ctx context.Context, createToken CreateUserToken) (int, error)
+	GetUserToken(ctx context.Context, purpose, tokenHash string) (*UserToken, error)
+	UseUserToken(ctx context.Context, id int, usedAt time.Time) (bool, error)
+	DeleteUserTokens(ctx context.Context, userID int, purpose string) error
+}
+
+type RecoveryCodes interface {
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string, createdAt time.Time) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type APITokens interface {
+	CreateAPIToken(ctx context.Context, createToken CreateAPIToken) (int, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	GetAPITokensByUser(ctx context.Context, userID int) ([]*APIToken, error)
+	TouchAPIToken(ctx context.Context, id int, lastUsedAt time.Time) error
+	RevokeAPIToken(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error)
+	RevokeUserAPITokens(ctx context.Context, userID int, revokedAt time.Time) error
+}
+
+type LoginFailures interface {
+	GetLoginFailures(ctx context.Context, keys []string) ([]*LoginFailure, error)
+	AddLoginFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error)
+	LockLogin(ctx context.Context, key string, lockedUntil time.Time) error
+	DeleteLoginFailures(ctx context.Context, key string) error
+}
+
+type Audit interface {
+	CreateAuditEntry(ctx context.Context, entry CreateAuditEntry) error
+}
+
+type UserIdentities interface {
+	GetUserIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error)
+	CreateUserIdentity(ctx context.Context, createIdentity CreateUserIdentity) (int, error)
+	ProvisionUser(ctx context.Context, provision ProvisionUser) (int, error)
+	GetUserIdentitiesByUser(ctx context.Context, userID int) ([]*UserIdentity, error)
 }
 
 type Repositories struct {
-	Users     *UsersRepo
-	Posts     *PostsRepo
-	Tags      *TagsRepo
-	PostsTags *PostsTagsRepo
+	Users          *UsersRepo
+	Posts          *PostsRepo
+	Tags           *TagsRepo
+	RefreshTokens  *RefreshTokensRepo
+	Sessions       *SessionsRepo
+	UserTokens     *UserTokensRepo
+	RecoveryCodes  *RecoveryCodesRepo
+	APITokens      *APITokensRepo
+	LoginFailures  *LoginFailuresRepo
+	Audit          *AuditRepo
+	UserIdentities *UserIdentitiesRepo
+	SlugRedirects  *SlugRedirectsRepo
+	PostRevisions  *PostRevisionsRepo
 }
 
 func NewRepositories(db *sqlx.DB, log logger.Logger) *Repositories {
 	return &Repositories{
-		Users:     NewUsersRepo(db, log),
-		Posts:     NewPostsRepo(db, log),
-		Tags:      NewTagsRepo(db, log),
-		PostsTags: NewPostsTagsRepo(db, log),
+		Users:          NewUsersRepo(db, log),
+		Posts:          NewPostsRepo(db, log),
+		Tags:           NewTagsRepo(db, log),
+		RefreshTokens:  NewRefreshTokensRepo(db, log),
+		Sessions:       NewSessionsRepo(db, log),
+		UserTokens:     NewUserTokensRepo(db, log),
+		RecoveryCodes:  NewRecoveryCodesRepo(db, log),
+		APITokens:      NewAPITokensRepo(db, log),
+		LoginFailures:  NewLoginFailuresRepo(db, log),
+		Audit:          NewAuditRepo(db, log),
+		UserIdentities: NewUserIdentitiesRepo(db, log),
+		SlugRedirects:  NewSlugRedirectsRepo(db, log),
+		PostRevisions:  NewPostRevisionsRepo(db, log),
 	}
 }
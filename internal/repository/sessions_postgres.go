@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const sessionsTable = "sessions"
+
+type Session struct {
+	ID         int            `db:"id"`
+	UserID     int            `db:"user_id"`
+	TokenHash  string         `db:"token_hash"`
+	IP         sql.NullString `db:"ip"`
+	UserAgent  sql.NullString `db:"user_agent"`
+	CreatedAt  time.Time      `db:"created_at"`
+	LastSeenAt time.Time      `db:"last_seen_at"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+}
+
+type CreateSession struct {
+	UserID    int
+	TokenHash string
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type SessionsRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewSessionsRepo(db *sqlx.DB, log logger.Logger) *SessionsRepo {
+	return &SessionsRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *SessionsRepo) CreateSession(ctx context.Context, createSession CreateSession) (int, error) {
+	var id int
+	query, args, _ := squirrel.Insert(sessionsTable).
+		SetMap(map[string]interface{}{
+			"user_id":      createSession.UserID,
+			"token_hash":   createSession.TokenHash,
+			"ip":           createSession.IP,
+			"user_agent":   createSession.UserAgent,
+			"created_at":   createSession.CreatedAt,
+			"last_seen_at": createSession.CreatedAt,
+			"expires_at":   createSession.ExpiresAt,
+		}).
+		Suffix("RETURNING \"id\"").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *SessionsRepo) GetSessionByHash(ctx context.Context, tokenHash string) (*Session, error) {
+	return r.getSession(ctx, squirrel.Eq{"token_hash": tokenHash})
+}
+
+func (r *SessionsRepo) GetSessionByID(ctx context.Context, id int) (*Session, error) {
+	return r.getSession(ctx, squirrel.Eq{"id": id})
+}
+
+func (r *SessionsRepo) GetActiveSessionsByUser(ctx context.Context, userID int, now time.Time) ([]*Session, error) {
+	out := make([]*Session, 0)
+	query, args, _ := squirrel.Select("id, user_id, token_hash, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at").
+		From(sessionsTable).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		OrderBy("last_seen_at DESC").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *SessionsRepo) TouchSession(ctx context.Context, id int, lastSeenAt time.Time) error {
+	query, args, _ := squirrel.Update(sessionsTable).
+		Set("last_seen_at", lastSeenAt).
+		Where("id = ?", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *SessionsRepo) RevokeSession(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error) {
+	query, args, _ := squirrel.Update(sessionsTable).
+		Set("revoked_at", revokedAt).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *SessionsRepo) RevokeUserSessions(ctx context.Context, userID, exceptID int, revokedAt time.Time) error {
+	query, args, _ := squirrel.Update(sessionsTable).
+		Set("revoked_at", revokedAt).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *SessionsRepo) getSession(ctx context.Context, where squirrel.Sqlizer) (*Session, error) {
+	var s Session
+	query, args, _ := squirrel.Select("id, user_id, token_hash, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at").
+		From(sessionsTable).
+		Where(where).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.GetContext(ctx, &s, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
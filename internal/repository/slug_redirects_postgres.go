@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const slugRedirectsTable = "slug_redirects"
+
+type SlugRedirect struct {
+	ID         int            `db:"id"`
+	Kind       string         `db:"kind"`
+	Slug       string         `db:"slug"`
+	TargetID   int            `db:"target_id"`
+	TargetSlug sql.NullString `db:"target_slug"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+type SlugRedirectsRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewSlugRedirectsRepo(db *sqlx.DB, log logger.Logger) *SlugRedirectsRepo {
+	return &SlugRedirectsRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *SlugRedirectsRepo) GetSlugRedirect(ctx context.Context, kind, slug string) (*SlugRedirect, error) {
+	query, args, _ := r.selectRedirects().
+		Where(squirrel.Eq{"r.kind": kind, "r.slug": slug}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	var redirect SlugRedirect
+	err := r.db.GetContext(ctx, &redirect, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &redirect, nil
+}
+
+func (r *SlugRedirectsRepo) GetSlugRedirects(ctx context.Context, kind string) ([]*SlugRedirect, error) {
+	sb := r.selectRedirects().OrderBy("r.created_at DESC", "r.id DESC")
+	if kind != "" {
+		sb = sb.Where(squirrel.Eq{"r.kind": kind})
+	}
+	query, args, _ := sb.PlaceholderFormat(squirrel.Dollar).ToSql()
+	out := make([]*SlugRedirect, 0)
+	if err := r.db.SelectContext(ctx, &out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *SlugRedirectsRepo) DeleteSlugRedirect(ctx context.Context, id int) error {
+	query, args, _ := squirrel.Delete(slugRedirectsTable).
+		Where("id = ?", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SlugRedirectsRepo) DeleteSlugRedirectsByTarget(ctx context.Context, kind string, targetID int) error {
+	query, args, _ := squirrel.Delete(slugRedirectsTable).
+		Where(squirrel.Eq{"kind": kind, "target_id": targetID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// selectRedirects joins the current slug of each target. Redirects to
+// deleted posts or tags come back without one.
+func (r *SlugRedirectsRepo) selectRedirects() squirrel.SelectBuilder {
+	return squirrel.Select(`
+			r.id,
+			r.kind,
+			r.slug,
+			r.target_id,
+			COALESCE(p.slug, t.slug) AS target_slug,
+			r.created_at`).
+		From(slugRedirectsTable + " r").
+		LeftJoin(fmt.Sprintf("%s p ON r.kind = '%s' AND p.id = r.target_id AND p.status <> %d",
+			postsTable, domain.RedirectKindPost, domain.PostStatusDeleted)).
+		LeftJoin(fmt.Sprintf("%s t ON r.kind = '%s' AND t.id = r.target_id", tagsTable, domain.RedirectKindTag))
+}
+
+// moveSlug points the previous slug of a target at it and drops redirects
+// that the new slug now shadows, as part of the transaction writing the
+// target.
+func moveSlug(ctx context.Context, tx *sqlx.Tx, kind string, targetID int, oldSlug, newSlug string, at time.Time) error {
+	query, args, _ := squirrel.Delete(slugRedirectsTable).
+		Where(squirrel.Eq{"kind": kind, "slug": newSlug}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+	query, args, _ = squirrel.Insert(slugRedirectsTable).
+		SetMap(map[string]interface{}{
+			"kind":       kind,
+			"slug":       oldSlug,
+			"target_id":  targetID,
+			"created_at": at,
+		}).
+		Suffix("ON CONFLICT (kind, slug) DO UPDATE SET target_id = EXCLUDED.target_id, created_at = EXCLUDED.created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
@@ -5,10 +5,16 @@ import (
 	"database/sql"
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/internal/domain"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
 )
 
-const tagsTable = "tags"
+const (
+	tagsTable   = "tags"
+	tagsSlugKey = "tags_slug_key"
+	tagsNameKey = "tags_name_key"
+)
 
 type Tag struct {
 	ID   int    `db:"t_id"`
@@ -17,14 +23,18 @@ type Tag struct {
 }
 
 type CreateTag struct {
-	Name string
-	Slug string
+	Name      string
+	Slug      string
+	CreatedAt time.Time
 }
 
 type UpdateTag struct {
 	ID   int
 	Name string
 	Slug string
+	// PreviousSlug keeps redirecting to the tag when the slug changes.
+	PreviousSlug string
+	UpdatedAt    time.Time
 }
 
 type DeleteTag struct {
@@ -66,6 +76,34 @@ func (r *TagsRepo) GetTagByID(ctx context.Context, id int) (*Tag, error) {
 	return out[0], err
 }
 
+func (r *TagsRepo) GetTagBySlug(ctx context.Context, slug string) (*Tag, error) {
+	query, args, _ := squirrel.Select(`
+			t.id AS t_id,
+			t.name AS t_name,
+			t.slug AS t_slug
+		`).
+		From(tagsTable+" t").
+		Where("t.slug = ?", slug).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out, err := scanTagRows(rows)
+	if err == sql.ErrNoRows || len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out[0], err
+}
+
+// GetSlugsLike lists tag slugs equal to base or made of base and a suffix.
+func (r *TagsRepo) GetSlugsLike(ctx context.Context, base string, excludeID int) ([]string, error) {
+	return getSlugsLike(ctx, r.db, tagsTable, base, excludeID)
+}
+
 func (r *TagsRepo) GetTags(ctx context.Context) ([]*Tag, error) {
 	query, _, _ := squirrel.Select(`
 			t.id AS t_id,
@@ -91,7 +129,15 @@ func (r *TagsRepo) GetTags(ctx context.Context) ([]*Tag, error) {
 	return out, nil
 }
 
+// CreateTag inserts the tag and drops redirects its slug shadows. A name or
+// slug that is already taken yields ErrNameTaken or ErrSlugTaken.
 func (r *TagsRepo) CreateTag(ctx context.Context, createTag CreateTag) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	var id int
 	query, args, _ := squirrel.Insert(tagsTable).
 		SetMap(map[string]interface{}{
@@ -101,23 +147,25 @@ func (r *TagsRepo) CreateTag(ctx context.Context, createTag CreateTag) (int, err
 		Suffix("RETURNING \"id\"").
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
+	err = tx.QueryRowxContext(ctx, query, args...).Scan(&id)
+	if err = tagWriteError(err); err != nil {
 		return 0, err
 	}
-	defer func() { _ = rows.Close() }()
-	if rows.Next() {
-		if err = rows.Err(); err != nil {
-			return 0, err
-		}
-		if err = rows.Scan(&id); err != nil {
-			return 0, err
-		}
+	if err := moveSlug(ctx, tx, domain.RedirectKindTag, id, "", createTag.Slug, createTag.CreatedAt); err != nil {
+		return 0, err
 	}
-	return id, nil
+	return id, tx.Commit()
 }
 
+// UpdateTag renames the tag and keeps its previous slug as a redirect. A
+// name or slug that is already taken yields ErrNameTaken or ErrSlugTaken.
 func (r *TagsRepo) UpdateTag(ctx context.Context, updateTag UpdateTag) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	query, args, _ := squirrel.Update(tagsTable).
 		SetMap(map[string]interface{}{
 			"name": updateTag.Name,
@@ -126,8 +174,14 @@ func (r *TagsRepo) UpdateTag(ctx context.Context, updateTag UpdateTag) error {
 		Where("id = ?", updateTag.ID).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err = tagWriteError(err); err != nil {
+		return err
+	}
+	if err := moveSlug(ctx, tx, domain.RedirectKindTag, updateTag.ID, updateTag.PreviousSlug, updateTag.Slug, updateTag.UpdatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *TagsRepo) DeleteTag(ctx context.Context, deleteTag DeleteTag) error {
@@ -142,6 +196,16 @@ func (r *TagsRepo) DeleteTag(ctx context.Context, deleteTag DeleteTag) error {
 	return nil
 }
 
+func tagWriteError(err error) error {
+	switch {
+	case isUniqueViolation(err, tagsSlugKey):
+		return ErrSlugTaken
+	case isUniqueViolation(err, tagsNameKey):
+		return ErrNameTaken
+	}
+	return err
+}
+
 func scanTagRows(rows *sqlx.Rows) ([]*Tag, error) {
 	out := make([]*Tag, 0)
 	for rows.Next() {
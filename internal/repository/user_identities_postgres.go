@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const userIdentitiesTable = "user_identities"
+
+type UserIdentity struct {
+	ID        int            `db:"id"`
+	UserID    int            `db:"user_id"`
+	Issuer    string         `db:"issuer"`
+	Subject   string         `db:"subject"`
+	Email     sql.NullString `db:"email"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+type CreateUserIdentity struct {
+	UserID    int
+	Issuer    string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// ProvisionUser is a user created on first sign in through an identity
+// provider, along with the identity it signs in with.
+type ProvisionUser struct {
+	User domain.User
+	// EmailVerifiedAt is set when the provider vouched for the email.
+	EmailVerifiedAt sql.NullTime
+	Identity        CreateUserIdentity
+}
+
+type UserIdentitiesRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewUserIdentitiesRepo(db *sqlx.DB, log logger.Logger) *UserIdentitiesRepo {
+	return &UserIdentitiesRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *UserIdentitiesRepo) GetUserIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error) {
+	var i UserIdentity
+	query, args, _ := squirrel.Select("id, user_id, issuer, subject, email, created_at").
+		From(userIdentitiesTable).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.GetContext(ctx, &i, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *UserIdentitiesRepo) CreateUserIdentity(ctx context.Context, createIdentity CreateUserIdentity) (int, error) {
+	return createUserIdentity(ctx, r.db, createIdentity)
+}
+
+// ProvisionUser creates the user and links the identity to it in one
+// transaction, so a failed link doesn't leave a user holding the username.
+func (r *UserIdentitiesRepo) ProvisionUser(ctx context.Context, provision ProvisionUser) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int
+	user := provision.User
+	query := fmt.Sprintf("INSERT INTO %s (username, password_hash, role, email, email_verified_at) values ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id", usersTable)
+	err = tx.QueryRowxContext(ctx, query, user.Username, user.PasswordHash, user.Role, user.Email, provision.EmailVerifiedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	provision.Identity.UserID = id
+	if _, err := createUserIdentity(ctx, tx, provision.Identity); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func createUserIdentity(ctx context.Context, q sqlx.QueryerContext, createIdentity CreateUserIdentity) (int, error) {
+	var id int
+	query, args, _ := squirrel.Insert(userIdentitiesTable).
+		SetMap(map[string]interface{}{
+			"user_id":    createIdentity.UserID,
+			"issuer":     createIdentity.Issuer,
+			"subject":    createIdentity.Subject,
+			"email":      sql.NullString{String: createIdentity.Email, Valid: createIdentity.Email != ""},
+			"created_at": createIdentity.CreatedAt,
+		}).
+		Suffix("RETURNING \"id\"").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := q.QueryRowxContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *UserIdentitiesRepo) GetUserIdentitiesByUser(ctx context.Context, userID int) ([]*UserIdentity, error) {
+	identities := make([]*UserIdentity, 0)
+	query, args, _ := squirrel.Select("id, user_id, issuer, subject, email, created_at").
+		From(userIdentitiesTable).
+		Where("user_id = ?", userID).
+		OrderBy("id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.SelectContext(ctx, &identities, query, args...)
+	return identities, err
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const userTokensTable = "user_tokens"
+
+type UserToken struct {
+	ID        int          `db:"id"`
+	UserID    int          `db:"user_id"`
+	Purpose   string       `db:"purpose"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	CreatedAt time.Time    `db:"created_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+}
+
+type CreateUserToken struct {
+	UserID    int
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type UserTokensRepo struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewUserTokensRepo(db *sqlx.DB, log logger.Logger) *UserTokensRepo {
+	return &UserTokensRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *UserTokensRepo) CreateUserToken(ctx context.Context, createToken CreateUserToken) (int, error) {
+	var id int
+	query, args, _ := squirrel.Insert(userTokensTable).
+		SetMap(map[string]interface{}{
+			"user_id":    createToken.UserID,
+			"purpose":    createToken.Purpose,
+			"token_hash": createToken.TokenHash,
+			"expires_at": createToken.ExpiresAt,
+			"created_at": createToken.CreatedAt,
+		}).
+		Suffix("RETURNING \"id\"").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *UserTokensRepo) GetUserToken(ctx context.Context, purpose, tokenHash string) (*UserToken, error) {
+	var t UserToken
+	query, args, _ := squirrel.Select("id, user_id, purpose, token_hash, expires_at, created_at, used_at").
+		From(userTokensTable).
+		Where("purpose = ? AND token_hash = ?", purpose, tokenHash).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	err := r.db.GetContext(ctx, &t, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// UseUserToken consumes the token and reports whether it was still unused.
+func (r *UserTokensRepo) UseUserToken(ctx context.Context, id int, usedAt time.Time) (bool, error) {
+	query, args, _ := squirrel.Update(userTokensTable).
+		Set("used_at", usedAt).
+		Where("id = ? AND used_at IS NULL", id).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *UserTokensRepo) DeleteUserTokens(ctx context.Context, userID int, purpose string) error {
+	query, args, _ := squirrel.Delete(userTokensTable).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
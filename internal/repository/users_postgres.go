@@ -5,11 +5,44 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/scraletteykt/my-blog/internal/domain"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
 )
 
-const usersTable = "users"
+const (
+	usersTable  = "users"
+	userColumns = "id, username, password_hash, role, COALESCE(email, '') AS email, email_verified_at IS NOT NULL AS email_verified, " +
+		"COALESCE(totp_secret, '') AS totp_secret, totp_enabled, deleted_at IS NOT NULL AS deleted"
+	profileColumns = "id, username, display_name, bio, avatar_url, website, social_links"
+)
+
+type Profile struct {
+	UserID      int    `db:"id"`
+	Username    string `db:"username"`
+	DisplayName string `db:"display_name"`
+	Bio         string `db:"bio"`
+	AvatarURL   string `db:"avatar_url"`
+	Website     string `db:"website"`
+	SocialLinks []byte `db:"social_links"`
+}
+
+type DeletionRequest struct {
+	UserID      int       `db:"id"`
+	Username    string    `db:"username"`
+	Email       string    `db:"email"`
+	RequestedAt time.Time `db:"deletion_requested_at"`
+}
+
+type UpdateProfile struct {
+	UserID      int
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+	Website     string
+	SocialLinks []byte
+}
 
 type UsersRepo struct {
 	db  *sqlx.DB
@@ -25,9 +58,9 @@ func NewUsersRepo(db *sqlx.DB, log logger.Logger) *UsersRepo {
 
 func (r *UsersRepo) CreateUser(ctx context.Context, user domain.User) (int, error) {
 	var id int
-	query := fmt.Sprintf("INSERT INTO %s (username, password_hash) values ($1, $2) RETURNING id", usersTable)
+	query := fmt.Sprintf("INSERT INTO %s (username, password_hash, role, email) values ($1, $2, $3, NULLIF($4, '')) RETURNING id", usersTable)
 
-	row := r.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash)
+	row := r.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Role, user.Email)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
@@ -37,7 +70,7 @@ func (r *UsersRepo) CreateUser(ctx context.Context, user domain.User) (int, erro
 
 func (r *UsersRepo) GetUser(ctx context.Context, username string) (*domain.User, error) {
 	var user domain.User
-	query := fmt.Sprintf("SELECT id, username, password_hash FROM %s WHERE username=$1", usersTable)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE username=$1", userColumns, usersTable)
 	err := r.db.GetContext(ctx, &user, query, username)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -47,10 +80,176 @@ func (r *UsersRepo) GetUser(ctx context.Context, username string) (*domain.User,
 
 func (r *UsersRepo) GetUserByID(ctx context.Context, id int) (*domain.User, error) {
 	var user domain.User
-	query := fmt.Sprintf("SELECT id, username, password_hash FROM %s WHERE id=$1", usersTable)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id=$1", userColumns, usersTable)
 	err := r.db.GetContext(ctx, &user, query, id)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
 	return &user, err
 }
+
+func (r *UsersRepo) GetUsers(ctx context.Context) ([]*domain.User, error) {
+	users := make([]*domain.User, 0)
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY id", userColumns, usersTable)
+	err := r.db.SelectContext(ctx, &users, query)
+	return users, err
+}
+
+func (r *UsersRepo) UpdateUserRole(ctx context.Context, id int, role string) error {
+	query := fmt.Sprintf("UPDATE %s SET role=$1 WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, role, id)
+	return err
+}
+
+func (r *UsersRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var user domain.User
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE lower(email)=lower($1)", userColumns, usersTable)
+	err := r.db.GetContext(ctx, &user, query, email)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &user, err
+}
+
+func (r *UsersRepo) SetEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET email_verified_at=$1 WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, verifiedAt, id)
+	return err
+}
+
+func (r *UsersRepo) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error {
+	query := fmt.Sprintf("UPDATE %s SET password_hash=$1 WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	return err
+}
+
+func (r *UsersRepo) SetTOTPSecret(ctx context.Context, id int, secret string) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_secret=NULLIF($1, ''), totp_enabled=false, totp_last_step=NULL WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, secret, id)
+	return err
+}
+
+func (r *UsersRepo) SetTOTPEnabled(ctx context.Context, id int, enabled bool) error {
+	query := fmt.Sprintf("UPDATE %s SET totp_enabled=$1 WHERE id=$2", usersTable)
+	_, err := r.db.ExecContext(ctx, query, enabled, id)
+	return err
+}
+
+// UseTOTPStep records the step of an accepted code and reports false when
+// that step, or a later one, was already used.
+func (r *UsersRepo) UseTOTPStep(ctx context.Context, id int, step int64) (bool, error) {
+	query := fmt.Sprintf("UPDATE %s SET totp_last_step=$1 WHERE id=$2 AND (totp_last_step IS NULL OR totp_last_step < $1)", usersTable)
+	res, err := r.db.ExecContext(ctx, query, step, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *UsersRepo) GetProfile(ctx context.Context, username string) (*Profile, error) {
+	var profile Profile
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE username=$1 AND deleted_at IS NULL", profileColumns, usersTable)
+	err := r.db.GetContext(ctx, &profile, query, username)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &profile, err
+}
+
+func (r *UsersRepo) GetProfileByID(ctx context.Context, id int) (*Profile, error) {
+	var profile Profile
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id=$1", profileColumns, usersTable)
+	err := r.db.GetContext(ctx, &profile, query, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &profile, err
+}
+
+func (r *UsersRepo) UpdateProfile(ctx context.Context, updateProfile UpdateProfile) error {
+	query := fmt.Sprintf("UPDATE %s SET display_name=$1, bio=$2, avatar_url=$3, website=$4, social_links=$5 WHERE id=$6", usersTable)
+	_, err := r.db.ExecContext(ctx, query, updateProfile.DisplayName, updateProfile.Bio, updateProfile.AvatarURL,
+		updateProfile.Website, updateProfile.SocialLinks, updateProfile.UserID)
+	return err
+}
+
+func (r *UsersRepo) SetDeletionRequested(ctx context.Context, id int, requestedAt sql.NullTime) error {
+	query := fmt.Sprintf("UPDATE %s SET deletion_requested_at=$1 WHERE id=$2 AND deleted_at IS NULL", usersTable)
+	_, err := r.db.ExecContext(ctx, query, requestedAt, id)
+	return err
+}
+
+func (r *UsersRepo) GetDeletionRequests(ctx context.Context) ([]*DeletionRequest, error) {
+	requests := make([]*DeletionRequest, 0)
+	query := fmt.Sprintf("SELECT id, username, COALESCE(email, '') AS email, deletion_requested_at FROM %s "+
+		"WHERE deletion_requested_at IS NOT NULL AND deleted_at IS NULL ORDER BY deletion_requested_at", usersTable)
+	err := r.db.SelectContext(ctx, &requests, query)
+	return requests, err
+}
+
+// AnonymizeUser scrubs personal data and credentials but keeps the row, so
+// the user's posts stay in place under an anonymous author. The login
+// failure counters under the given keys go too.
+func (r *UsersRepo) AnonymizeUser(ctx context.Context, id int, deletedAt time.Time, loginFailureKeys []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := fmt.Sprintf("UPDATE %s SET username='deleted-' || id, password_hash='!deleted', role='reader', "+
+		"email=NULL, email_verified_at=NULL, totp_secret=NULL, totp_enabled=false, totp_last_step=NULL, "+
+		"display_name='Deleted user', bio='', avatar_url='', website='', social_links='{}', "+
+		"deletion_requested_at=NULL, deleted_at=$1 WHERE id=$2", usersTable)
+	if _, err := tx.ExecContext(ctx, query, deletedAt, id); err != nil {
+		return err
+	}
+	for _, table := range []string{sessionsTable, userTokensTable, recoveryCodesTable, apiTokensTable, userIdentitiesTable} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE user_id=$1", table), id); err != nil {
+			return err
+		}
+	}
+	if err := scrubUserTraces(ctx, tx, id, loginFailureKeys); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TransferAndDeleteUser hands every post over to another user and removes
+// the account; credentials and sessions go with it through cascades, the
+// login failure counters under the given keys are deleted.
+func (r *UsersRepo) TransferAndDeleteUser(ctx context.Context, id, toID int, loginFailureKeys []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET user_id=$1 WHERE user_id=$2", postsTable), toID, id); err != nil {
+		return err
+	}
+	if err := scrubUserTraces(ctx, tx, id, loginFailureKeys); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id=$1", usersTable), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scrubUserTraces drops what sign in throttling and the audit log still
+// tie to a deleted user: the failure counters under its keys and the
+// addresses it acted from.
+func scrubUserTraces(ctx context.Context, tx *sqlx.Tx, id int, loginFailureKeys []string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE key = ANY($1)", loginFailuresTable)
+	if _, err := tx.ExecContext(ctx, query, pq.StringArray(loginFailureKeys)); err != nil {
+		return err
+	}
+	query = fmt.Sprintf("UPDATE %s SET ip=NULL WHERE user_id=$1", auditLogTable)
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"sort"
+	"time"
+)
+
+const (
+	auditActionDeletionRequested = "account_deletion_requested"
+	auditActionAccountDeleted    = "account_deleted"
+	// exportPostsLimit lifts the default page size so an export holds every post.
+	exportPostsLimit = 1 << 20
+)
+
+var ErrInvalidTransferTarget = errors.New("posts can only be transferred to another active user")
+
+type AccountDataService struct {
+	usersRepo      repository.UsersRepo
+	postsRepo      repository.PostsRepo
+	sessionsRepo   repository.SessionsRepo
+	apiTokensRepo  repository.APITokensRepo
+	identitiesRepo repository.UserIdentitiesRepo
+	auditRepo      repository.AuditRepo
+	log            logger.Logger
+}
+
+func NewAccountDataService(usersRepo repository.UsersRepo, postsRepo repository.PostsRepo, sessionsRepo repository.SessionsRepo,
+	apiTokensRepo repository.APITokensRepo, identitiesRepo repository.UserIdentitiesRepo, auditRepo repository.AuditRepo, log logger.Logger) *AccountDataService {
+	return &AccountDataService{
+		usersRepo:      usersRepo,
+		postsRepo:      postsRepo,
+		sessionsRepo:   sessionsRepo,
+		apiTokensRepo:  apiTokensRepo,
+		identitiesRepo: identitiesRepo,
+		auditRepo:      auditRepo,
+		log:            log,
+	}
+}
+
+// Export collects everything stored about the user, including draft and
+// deleted posts.
+func (s *AccountDataService) Export(ctx context.Context, userID int) (*domain.AccountExport, error) {
+	user, err := s.usersRepo.GetUserByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	profileDB, err := s.usersRepo.GetProfileByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := toProfile(profileDB)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &domain.AccountExport{
+		ExportedAt: time.Now(),
+		Account:    user,
+		Profile:    profile,
+		Posts:      make([]*domain.Post, 0),
+		Tags:       make([]*domain.Tag, 0),
+		Sessions:   make([]*domain.Session, 0),
+		APITokens:  make([]*domain.APIToken, 0),
+		Identities: make([]*domain.UserIdentity, 0),
+	}
+
+	dbPosts, err := s.postsRepo.GetPostsByCriteria(ctx, repository.PostCriteria{
+		UserID:         userID,
+		IncludeDeleted: true,
+		Limit:          exportPostsLimit,
+	})
+	if err != nil && err != repository.ErrNotFound {
+		return nil, err
+	}
+	tags := make(map[int]*domain.Tag)
+	for _, dbPost := range dbPosts {
+		post := toDomainPost(dbPost)
+		export.Posts = append(export.Posts, post)
+		for _, t := range post.Tags {
+			tags[t.ID] = t
+		}
+	}
+	sort.Slice(export.Posts, func(i, j int) bool { return export.Posts[i].ID < export.Posts[j].ID })
+	for _, t := range tags {
+		export.Tags = append(export.Tags, t)
+	}
+	sort.Slice(export.Tags, func(i, j int) bool { return export.Tags[i].ID < export.Tags[j].ID })
+
+	dbSessions, err := s.sessionsRepo.GetActiveSessionsByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, dbSession := range dbSessions {
+		export.Sessions = append(export.Sessions, toDomainSession(dbSession))
+	}
+
+	dbTokens, err := s.apiTokensRepo.GetAPITokensByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range dbTokens {
+		export.APITokens = append(export.APITokens, toDomainAPIToken(t))
+	}
+
+	dbIdentities, err := s.identitiesRepo.GetUserIdentitiesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range dbIdentities {
+		export.Identities = append(export.Identities, &domain.UserIdentity{
+			Issuer:    i.Issuer,
+			Subject:   i.Subject,
+			Email:     i.Email.String,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+	return export, nil
+}
+
+func (s *AccountDataService) RequestDeletion(ctx context.Context, userID int, ip string) error {
+	now := time.Now()
+	err := s.usersRepo.SetDeletionRequested(ctx, userID, sql.NullTime{Time: now, Valid: true})
+	if err != nil {
+		return err
+	}
+	return s.audit(ctx, auditActionDeletionRequested, userID, ip, "", now)
+}
+
+func (s *AccountDataService) CancelDeletion(ctx context.Context, userID int) error {
+	return s.usersRepo.SetDeletionRequested(ctx, userID, sql.NullTime{})
+}
+
+func (s *AccountDataService) GetDeletionRequests(ctx context.Context) ([]*domain.DeletionRequest, error) {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return nil, err
+	}
+	dbRequests, err := s.usersRepo.GetDeletionRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.DeletionRequest, 0, len(dbRequests))
+	for _, r := range dbRequests {
+		out = append(out, &domain.DeletionRequest{
+			UserID:      r.UserID,
+			Username:    r.Username,
+			Email:       r.Email,
+			RequestedAt: r.RequestedAt,
+		})
+	}
+	return out, nil
+}
+
+func (s *AccountDataService) DeleteAccount(ctx context.Context, deleteAccount domain.DeleteAccount, ip string) error {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return err
+	}
+	actor := auth.FromContext(ctx)
+	if actor.ID == deleteAccount.UserID {
+		return ErrAccessDenied
+	}
+	user, err := s.usersRepo.GetUserByID(ctx, deleteAccount.UserID)
+	if err == repository.ErrNotFound || (err == nil && user.Deleted) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	details := "posts anonymized"
+	keys := []string{accountKey(user.Username)}
+	if user.Email != "" {
+		keys = append(keys, mailKey(user.Email))
+	}
+	if deleteAccount.TransferTo > 0 {
+		target, err := s.usersRepo.GetUserByID(ctx, deleteAccount.TransferTo)
+		if err == repository.ErrNotFound || (err == nil && (target.Deleted || target.ID == user.ID)) {
+			return ErrInvalidTransferTarget
+		}
+		if err != nil {
+			return err
+		}
+		err = s.usersRepo.TransferAndDeleteUser(ctx, user.ID, target.ID, keys)
+		if err != nil {
+			return err
+		}
+		details = fmt.Sprintf("posts transferred to user %d", target.ID)
+	} else {
+		err = s.usersRepo.AnonymizeUser(ctx, user.ID, now, keys)
+		if err != nil {
+			return err
+		}
+	}
+	s.log.Infof("account %d (%s) deleted: %s", user.ID, user.Username, details)
+	// The address of a user deleting their own account is theirs too.
+	if actor.ID == user.ID {
+		ip = ""
+	}
+	return s.audit(ctx, auditActionAccountDeleted, actor.ID, ip, fmt.Sprintf("user %d: %s", user.ID, details), now)
+}
+
+func (s *AccountDataService) audit(ctx context.Context, action string, userID int, ip, details string, now time.Time) error {
+	return s.auditRepo.CreateAuditEntry(ctx, repository.CreateAuditEntry{
+		Action:    action,
+		UserID:    sql.NullInt32{Int32: int32(userID), Valid: userID > 0},
+		IP:        ip,
+		Details:   details,
+		CreatedAt: now,
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/mailer"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrEmailNotVerified = errors.New("email address is not verified")
+
+type AccountsService struct {
+	usersRepo      repository.UsersRepo
+	userTokensRepo repository.UserTokensRepo
+	sessionsRepo   repository.SessionsRepo
+	apiTokensRepo  repository.APITokensRepo
+	mailer         mailer.Mailer
+	baseURL        string
+	verifyTTL      time.Duration
+	resetTTL       time.Duration
+	log            logger.Logger
+}
+
+func NewAccountsService(usersRepo repository.UsersRepo, userTokensRepo repository.UserTokensRepo, sessionsRepo repository.SessionsRepo, apiTokensRepo repository.APITokensRepo, m mailer.Mailer, baseURL string, verifyTTL, resetTTL time.Duration, log logger.Logger) *AccountsService {
+	return &AccountsService{
+		usersRepo:      usersRepo,
+		userTokensRepo: userTokensRepo,
+		sessionsRepo:   sessionsRepo,
+		apiTokensRepo:  apiTokensRepo,
+		mailer:         m,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		verifyTTL:      verifyTTL,
+		resetTTL:       resetTTL,
+		log:            log,
+	}
+}
+
+func (s *AccountsService) SendVerificationEmail(ctx context.Context, user domain.User) error {
+	if user.Email == "" || user.EmailVerified {
+		return nil
+	}
+	if err := s.userTokensRepo.DeleteUserTokens(ctx, user.ID, userTokenPurposeVerifyEmail); err != nil {
+		return err
+	}
+	t, err := issueUserToken(ctx, s.userTokensRepo, user.ID, userTokenPurposeVerifyEmail, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, s.link("/verify-email", t), s.verifyTTL),
+	})
+}
+
+func (s *AccountsService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.usersRepo.GetUserByEmail(ctx, email)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.SendVerificationEmail(ctx, *user)
+}
+
+func (s *AccountsService) VerifyEmail(ctx context.Context, verifyToken string) error {
+	userID, err := useUserToken(ctx, s.userTokensRepo, userTokenPurposeVerifyEmail, verifyToken)
+	if err != nil {
+		return err
+	}
+	return s.usersRepo.SetEmailVerified(ctx, userID, time.Now())
+}
+
+func (s *AccountsService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.usersRepo.GetUserByEmail(ctx, email)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.userTokensRepo.DeleteUserTokens(ctx, user.ID, userTokenPurposeResetPassword); err != nil {
+		return err
+	}
+	t, err := issueUserToken(ctx, s.userTokensRepo, user.ID, userTokenPurposeResetPassword, s.resetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone requested a password reset for your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you did not request a reset, ignore this email.\n",
+			user.Username, s.link("/reset-password", t), s.resetTTL),
+	})
+}
+
+func (s *AccountsService) ResetPassword(ctx context.Context, resetToken, passwordHash string) error {
+	userID, err := useUserToken(ctx, s.userTokensRepo, userTokenPurposeResetPassword, resetToken)
+	if err != nil {
+		return err
+	}
+	if err := s.usersRepo.UpdatePasswordHash(ctx, userID, passwordHash); err != nil {
+		return err
+	}
+	// Whoever knew the old password must not stay signed in, nor keep
+	// the API tokens they may have created with it.
+	now := time.Now()
+	if err := s.sessionsRepo.RevokeUserSessions(ctx, userID, 0, now); err != nil {
+		return err
+	}
+	return s.apiTokensRepo.RevokeUserAPITokens(ctx, userID, now)
+}
+
+func (s *AccountsService) link(path, t string) string {
+	return s.baseURL + path + "?token=" + url.QueryEscape(t)
+}
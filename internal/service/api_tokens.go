@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/token"
+	"strings"
+	"time"
+)
+
+const (
+	APITokenPrefix       = "blogpat_"
+	apiTokenPrefixLength = 8
+	apiTokenTouchPeriod  = time.Minute
+)
+
+var (
+	ErrInvalidScope      = errors.New("invalid token scope")
+	ErrInvalidTokenName  = errors.New("token name must not be empty")
+	ErrInvalidExpiration = errors.New("token expiration must be in the future")
+)
+
+type APITokensService struct {
+	apiTokensRepo repository.APITokensRepo
+	usersRepo     repository.UsersRepo
+	defaultTTL    time.Duration
+	maxTTL        time.Duration
+	log           logger.Logger
+}
+
+func NewAPITokensService(apiTokensRepo repository.APITokensRepo, usersRepo repository.UsersRepo, defaultTTL, maxTTL time.Duration, log logger.Logger) *APITokensService {
+	return &APITokensService{
+		apiTokensRepo: apiTokensRepo,
+		usersRepo:     usersRepo,
+		defaultTTL:    defaultTTL,
+		maxTTL:        maxTTL,
+		log:           log,
+	}
+}
+
+func (s *APITokensService) CreateToken(ctx context.Context, createToken domain.CreateAPIToken) (*domain.CreatedAPIToken, error) {
+	u := auth.FromContext(ctx)
+	if u.ID != createToken.UserID || u.IsScoped() {
+		return nil, ErrAccessDenied
+	}
+	createToken.Name = strings.TrimSpace(createToken.Name)
+	if createToken.Name == "" {
+		return nil, ErrInvalidTokenName
+	}
+	if len(createToken.Scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, scope := range createToken.Scopes {
+		p := auth.Permission(scope)
+		if !auth.IsValidPermission(p) || !u.Can(p) {
+			return nil, ErrInvalidScope
+		}
+	}
+	now := time.Now()
+	if createToken.ExpiresAt.IsZero() {
+		createToken.ExpiresAt = now.Add(s.defaultTTL)
+	}
+	if !createToken.ExpiresAt.After(now) || createToken.ExpiresAt.Sub(now) > s.maxTTL {
+		return nil, ErrInvalidExpiration
+	}
+
+	secret, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	plain := APITokenPrefix + secret
+	prefix := APITokenPrefix + secret[:apiTokenPrefixLength]
+	id, err := s.apiTokensRepo.CreateAPIToken(ctx, repository.CreateAPIToken{
+		UserID:    createToken.UserID,
+		Name:      createToken.Name,
+		Prefix:    prefix,
+		TokenHash: token.Hash(plain),
+		Scopes:    createToken.Scopes,
+		ExpiresAt: createToken.ExpiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CreatedAPIToken{
+		APIToken: domain.APIToken{
+			ID:        id,
+			Name:      createToken.Name,
+			Prefix:    prefix,
+			Scopes:    createToken.Scopes,
+			ExpiresAt: createToken.ExpiresAt,
+			CreatedAt: now,
+		},
+		Token: plain,
+	}, nil
+}
+
+func (s *APITokensService) GetTokens(ctx context.Context, userID int) ([]*domain.APIToken, error) {
+	dbTokens, err := s.apiTokensRepo.GetAPITokensByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.APIToken, 0, len(dbTokens))
+	for _, t := range dbTokens {
+		out = append(out, toDomainAPIToken(t))
+	}
+	return out, nil
+}
+
+func (s *APITokensService) RevokeToken(ctx context.Context, userID, tokenID int) error {
+	ok, err := s.apiTokensRepo.RevokeAPIToken(ctx, tokenID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *APITokensService) Authenticate(ctx context.Context, plain string) (auth.User, error) {
+	dbToken, err := s.apiTokensRepo.GetAPITokenByHash(ctx, token.Hash(plain))
+	if err == repository.ErrNotFound {
+		return auth.User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return auth.User{}, err
+	}
+	now := time.Now()
+	if dbToken.RevokedAt.Valid || now.After(dbToken.ExpiresAt) {
+		return auth.User{}, ErrInvalidToken
+	}
+	userDB, err := s.usersRepo.GetUserByID(ctx, dbToken.UserID)
+	if err == repository.ErrNotFound {
+		return auth.User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return auth.User{}, err
+	}
+	if !dbToken.LastUsedAt.Valid || now.Sub(dbToken.LastUsedAt.Time) > apiTokenTouchPeriod {
+		if err := s.apiTokensRepo.TouchAPIToken(ctx, dbToken.ID, now); err != nil {
+			s.log.Warnf("warn: touch api token %d: %s", dbToken.ID, err.Error())
+		}
+	}
+	scopes := make([]auth.Permission, 0, len(dbToken.Scopes))
+	for _, scope := range dbToken.Scopes {
+		scopes = append(scopes, auth.Permission(scope))
+	}
+	return auth.User{
+		ID:       userDB.ID,
+		Username: userDB.Username,
+		Role:     userDB.Role,
+		Scopes:   scopes,
+	}, nil
+}
+
+func IsAPIToken(t string) bool {
+	return strings.HasPrefix(t, APITokenPrefix)
+}
+
+func toDomainAPIToken(t *repository.APIToken) *domain.APIToken {
+	return &domain.APIToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt.Time,
+		CreatedAt:  t.CreatedAt,
+	}
+}
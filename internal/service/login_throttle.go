@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"strings"
+	"time"
+)
+
+const auditActionLoginLockout = "login_lockout"
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed sign in attempts, try again later")
+	ErrTooManyEmails   = errors.New("too many emails requested, try again later")
+)
+
+type LoginThrottleService struct {
+	loginFailuresRepo repository.LoginFailuresRepo
+	auditRepo         repository.AuditRepo
+	cfg               config.LockoutConfig
+	log               logger.Logger
+}
+
+func NewLoginThrottleService(loginFailuresRepo repository.LoginFailuresRepo, auditRepo repository.AuditRepo, cfg config.LockoutConfig, log logger.Logger) *LoginThrottleService {
+	return &LoginThrottleService{
+		loginFailuresRepo: loginFailuresRepo,
+		auditRepo:         auditRepo,
+		cfg:               cfg,
+		log:               log,
+	}
+}
+
+// Check returns ErrTooManyAttempts together with the remaining wait time when
+// either the account or the client address is currently backed off or locked.
+func (s *LoginThrottleService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	failures, err := s.loginFailuresRepo.GetLoginFailures(ctx, []string{accountKey(username), ipKey(ip)})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, f := range failures {
+		if f.LockedUntil.Valid && f.LockedUntil.Time.After(now) {
+			if d := f.LockedUntil.Time.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return wait, ErrTooManyAttempts
+	}
+	return 0, nil
+}
+
+func (s *LoginThrottleService) RecordFailure(ctx context.Context, username, ip string, userID int) error {
+	if err := s.recordFailure(ctx, accountKey(username), s.cfg.FreeAttempts, s.cfg.LockoutThreshold, ip, userID); err != nil {
+		return err
+	}
+	return s.recordFailure(ctx, ipKey(ip), s.cfg.IPFreeAttempts, s.cfg.IPLockoutThreshold, ip, 0)
+}
+
+func (s *LoginThrottleService) RecordSuccess(ctx context.Context, username string) error {
+	return s.loginFailuresRepo.DeleteLoginFailures(ctx, accountKey(username))
+}
+
+// ThrottleMail counts an email requested without signing in, such as a
+// password reset, against the address it goes to and the client asking for
+// it. Once either has asked for too many within the window, it returns
+// ErrTooManyEmails with the remaining wait and the email must not be sent.
+// Unknown addresses count too, so the answer doesn't tell which exist.
+func (s *LoginThrottleService) ThrottleMail(ctx context.Context, email, ip string) (time.Duration, error) {
+	keys := []string{mailKey(email), mailIPKey(ip)}
+	failures, err := s.loginFailuresRepo.GetLoginFailures(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, f := range failures {
+		if f.LockedUntil.Valid && f.LockedUntil.Time.After(now) {
+			return f.LockedUntil.Time.Sub(now), ErrTooManyEmails
+		}
+	}
+	for i, limit := range []int{s.cfg.MailPerAddress, s.cfg.MailPerIP} {
+		sent, err := s.loginFailuresRepo.AddLoginFailure(ctx, keys[i], now, now.Add(-s.cfg.Window))
+		if err != nil {
+			return 0, err
+		}
+		if sent > limit {
+			if err := s.loginFailuresRepo.LockLogin(ctx, keys[i], now.Add(s.cfg.Window)); err != nil {
+				return 0, err
+			}
+			return s.cfg.Window, ErrTooManyEmails
+		}
+	}
+	return 0, nil
+}
+
+func (s *LoginThrottleService) recordFailure(ctx context.Context, key string, freeAttempts, lockoutThreshold int, ip string, userID int) error {
+	now := time.Now()
+	failures, err := s.loginFailuresRepo.AddLoginFailure(ctx, key, now, now.Add(-s.cfg.Window))
+	if err != nil {
+		return err
+	}
+	if failures >= lockoutThreshold {
+		if err := s.loginFailuresRepo.LockLogin(ctx, key, now.Add(s.cfg.LockoutDuration)); err != nil {
+			return err
+		}
+		if failures == lockoutThreshold {
+			return s.audit(ctx, key, ip, userID, failures, now)
+		}
+		return nil
+	}
+	if failures < freeAttempts {
+		return nil
+	}
+	return s.loginFailuresRepo.LockLogin(ctx, key, now.Add(s.backoff(failures-freeAttempts)))
+}
+
+func (s *LoginThrottleService) backoff(n int) time.Duration {
+	d := s.cfg.BaseDelay
+	for i := 0; i < n && d < s.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxDelay {
+		d = s.cfg.MaxDelay
+	}
+	return d
+}
+
+func (s *LoginThrottleService) audit(ctx context.Context, key, ip string, userID, failures int, now time.Time) error {
+	s.log.Warnf("warn: sign in locked for %s after %d failed attempts", key, failures)
+	return s.auditRepo.CreateAuditEntry(ctx, repository.CreateAuditEntry{
+		Action:    auditActionLoginLockout,
+		UserID:    sql.NullInt32{Int32: int32(userID), Valid: userID > 0},
+		IP:        ip,
+		Details:   fmt.Sprintf("%s locked for %s after %d failed attempts", key, s.cfg.LockoutDuration, failures),
+		CreatedAt: now,
+	})
+}
+
+func accountKey(username string) string {
+	return "account:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func mailKey(email string) string {
+	return "mail:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func mailIPKey(ip string) string {
+	return "mail-ip:" + ip
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/token"
+	"github.com/scraletteykt/my-blog/pkg/totp"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeBytes  = 5
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment was not started")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+)
+
+type MFAService struct {
+	usersRepo         repository.UsersRepo
+	userTokensRepo    repository.UserTokensRepo
+	recoveryCodesRepo repository.RecoveryCodesRepo
+	issuer            string
+	challengeTTL      time.Duration
+	log               logger.Logger
+}
+
+func NewMFAService(usersRepo repository.UsersRepo, userTokensRepo repository.UserTokensRepo, recoveryCodesRepo repository.RecoveryCodesRepo, issuer string, challengeTTL time.Duration, log logger.Logger) *MFAService {
+	return &MFAService{
+		usersRepo:         usersRepo,
+		userTokensRepo:    userTokensRepo,
+		recoveryCodesRepo: recoveryCodesRepo,
+		issuer:            issuer,
+		challengeTTL:      challengeTTL,
+		log:               log,
+	}
+}
+
+func (m *MFAService) Enroll(ctx context.Context, userID int) (*domain.TOTPEnrollment, error) {
+	user, err := m.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.usersRepo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(m.issuer, user.Username, secret),
+	}, nil
+}
+
+func (m *MFAService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := m.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := m.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+	if err := m.usersRepo.SetTOTPEnabled(ctx, userID, true); err != nil {
+		return nil, err
+	}
+	return m.replaceRecoveryCodes(ctx, userID)
+}
+
+func (m *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := m.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := m.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return m.replaceRecoveryCodes(ctx, userID)
+}
+
+// Disable turns two-factor authentication off. The caller is expected to have
+// re-checked the user's password; the second factor is checked here.
+func (m *MFAService) Disable(ctx context.Context, userID int, code string) error {
+	user, err := m.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := m.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+	if err := m.usersRepo.SetTOTPSecret(ctx, userID, ""); err != nil {
+		return err
+	}
+	return m.recoveryCodesRepo.ReplaceRecoveryCodes(ctx, userID, nil, time.Now())
+}
+
+func (m *MFAService) StartChallenge(ctx context.Context, user domain.User) (*domain.MFAChallenge, error) {
+	t, err := issueUserToken(ctx, m.userTokensRepo, user.ID, userTokenPurposeMFAChallenge, m.challengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    t,
+	}, nil
+}
+
+func (m *MFAService) CompleteChallenge(ctx context.Context, mfaToken, code string) (*domain.User, error) {
+	userID, err := useUserToken(ctx, m.userTokensRepo, userTokenPurposeMFAChallenge, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := m.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := m.verifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (m *MFAService) verifyCode(ctx context.Context, user *domain.User, code string) error {
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		return m.verifyTOTP(ctx, user, code)
+	}
+	ok, err := m.recoveryCodesRepo.UseRecoveryCode(ctx, user.ID, token.Hash(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (m *MFAService) verifyTOTP(ctx context.Context, user *domain.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, normalizeCode(code), time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	ok, err := m.usersRepo.UseTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (m *MFAService) replaceRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, token.Hash(code))
+	}
+	if err := m.recoveryCodesRepo.ReplaceRecoveryCodes(ctx, userID, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (m *MFAService) getUser(ctx context.Context, userID int) (*domain.User, error) {
+	user, err := m.usersRepo.GetUserByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
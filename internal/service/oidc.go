@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/oidc"
+	"strings"
+	"time"
+)
+
+// externalPasswordHash is stored for users provisioned from an identity
+// provider; it is not a valid hash, so password sign in always fails.
+const (
+	externalPasswordHash = "!external"
+	maxUsernameAttempts  = 100
+)
+
+var (
+	ErrOIDCDisabled        = errors.New("openid connect sign in is disabled")
+	ErrIdentityNotLinked   = errors.New("external identity is not linked to any user")
+	ErrIdentityLinked      = errors.New("external identity is already linked to another user")
+	ErrUsernameUnavailable = errors.New("cannot pick a free username")
+)
+
+type OIDCService struct {
+	usersRepo      repository.Users
+	identitiesRepo repository.UserIdentities
+	provider       *oidc.Provider
+	cfg            config.OIDCConfig
+	log            logger.Logger
+}
+
+func NewOIDCService(usersRepo repository.Users, identitiesRepo repository.UserIdentities, provider *oidc.Provider, cfg config.OIDCConfig, log logger.Logger) *OIDCService {
+	return &OIDCService{
+		usersRepo:      usersRepo,
+		identitiesRepo: identitiesRepo,
+		provider:       provider,
+		cfg:            cfg,
+		log:            log,
+	}
+}
+
+func (s *OIDCService) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	if !s.cfg.Enabled {
+		return "", ErrOIDCDisabled
+	}
+	return s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+}
+
+func (s *OIDCService) Login(ctx context.Context, code, verifier, nonce string) (*domain.User, error) {
+	claims, err := s.exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := s.identitiesRepo.GetUserIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return s.getUser(ctx, identity.UserID)
+	}
+	if err != repository.ErrNotFound {
+		return nil, err
+	}
+	if !s.cfg.AutoProvision {
+		return nil, ErrIdentityNotLinked
+	}
+	return s.provision(ctx, claims)
+}
+
+func (s *OIDCService) Link(ctx context.Context, userID int, code, verifier, nonce string) error {
+	claims, err := s.exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return err
+	}
+	identity, err := s.identitiesRepo.GetUserIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		if identity.UserID != userID {
+			return ErrIdentityLinked
+		}
+		return nil
+	}
+	if err != repository.ErrNotFound {
+		return err
+	}
+	_, err = s.identitiesRepo.CreateUserIdentity(ctx, repository.CreateUserIdentity{
+		UserID:    userID,
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+func (s *OIDCService) PostLoginURL() string {
+	return s.cfg.PostLoginURL
+}
+
+func (s *OIDCService) exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrOIDCDisabled
+	}
+	claims, err := s.provider.Exchange(ctx, code, verifier, nonce)
+	if err == oidc.ErrInvalidIDToken {
+		return nil, ErrInvalidToken
+	}
+	return claims, err
+}
+
+func (s *OIDCService) provision(ctx context.Context, claims *oidc.Claims) (*domain.User, error) {
+	username, err := s.freeUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	email := ""
+	if claims.EmailVerified {
+		if _, err := s.usersRepo.GetUserByEmail(ctx, claims.Email); err == repository.ErrNotFound {
+			email = claims.Email
+		}
+	}
+	role := s.cfg.DefaultRole
+	if !auth.IsValidRole(role) {
+		role = auth.RoleReader
+	}
+	now := time.Now()
+	id, err := s.identitiesRepo.ProvisionUser(ctx, repository.ProvisionUser{
+		User: domain.User{
+			Username:     username,
+			PasswordHash: externalPasswordHash,
+			Role:         role,
+			Email:        email,
+		},
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: email != ""},
+		Identity: repository.CreateUserIdentity{
+			Issuer:    claims.Issuer,
+			Subject:   claims.Subject,
+			Email:     claims.Email,
+			CreatedAt: now,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Infof("provisioned user %s (%d) for %s subject %s", username, id, claims.Issuer, claims.Subject)
+	return s.getUser(ctx, id)
+}
+
+func (s *OIDCService) freeUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := sanitizeUsername(claims.PreferredUsername)
+	if base == "" {
+		base = sanitizeUsername(strings.SplitN(claims.Email, "@", 2)[0])
+	}
+	if base == "" {
+		base = "user"
+	}
+	for i := 1; i <= maxUsernameAttempts; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		_, err := s.usersRepo.GetUser(ctx, candidate)
+		if err == repository.ErrNotFound {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", ErrUsernameUnavailable
+}
+
+func (s *OIDCService) getUser(ctx context.Context, id int) (*domain.User, error) {
+	user, err := s.usersRepo.GetUserByID(ctx, id)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/oidc"
+	"github.com/scraletteykt/my-blog/pkg/oidc/oidctest"
+)
+
+// oidcUsersRepo keeps users in memory, looked up by id and username.
+type oidcUsersRepo struct {
+	repository.Users
+	users []*domain.User
+}
+
+func (r *oidcUsersRepo) find(match func(u *domain.User) bool) (*domain.User, error) {
+	for _, u := range r.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *oidcUsersRepo) GetUser(ctx context.Context, username string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Username == username })
+}
+
+func (r *oidcUsersRepo) GetUserByID(ctx context.Context, id int) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.ID == id })
+}
+
+func (r *oidcUsersRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Email == email })
+}
+
+// oidcIdentitiesRepo links identities to the users of an oidcUsersRepo.
+type oidcIdentitiesRepo struct {
+	repository.UserIdentities
+	users       *oidcUsersRepo
+	identities  []repository.CreateUserIdentity
+	provisioned []repository.ProvisionUser
+}
+
+func (r *oidcIdentitiesRepo) GetUserIdentity(ctx context.Context, issuer, subject string) (*repository.UserIdentity, error) {
+	for _, i := range r.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return &repository.UserIdentity{UserID: i.UserID, Issuer: i.Issuer, Subject: i.Subject}, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *oidcIdentitiesRepo) ProvisionUser(ctx context.Context, provision repository.ProvisionUser) (int, error) {
+	user := provision.User
+	user.ID = len(r.users.users) + 1
+	r.users.users = append(r.users.users, &user)
+	provision.Identity.UserID = user.ID
+	r.identities = append(r.identities, provision.Identity)
+	r.provisioned = append(r.provisioned, provision)
+	return user.ID, nil
+}
+
+func newTestOIDCService(issuer *oidctest.Issuer, autoProvision bool) (*OIDCService, *oidcIdentitiesRepo) {
+	users := &oidcUsersRepo{users: []*domain.User{
+		{ID: 1, Username: "alice", Email: "alice@example.com", Role: auth.RoleAdmin},
+	}}
+	identities := &oidcIdentitiesRepo{users: users}
+	cfg := config.OIDCConfig{
+		Enabled:       true,
+		Issuer:        issuer.URL,
+		ClientID:      issuer.ClientID,
+		AutoProvision: autoProvision,
+		DefaultRole:   auth.RoleAuthor,
+	}
+	provider := oidc.NewProvider(oidc.Config{Issuer: cfg.Issuer, ClientID: cfg.ClientID}, issuer.Client())
+	return NewOIDCService(users, identities, provider, cfg, logger.NewLogger()), identities
+}
+
+func TestOIDCLoginLinkedIdentity(t *testing.T) {
+	issuer := oidctest.NewIssuer("my-blog")
+	defer issuer.Close()
+	s, identities := newTestOIDCService(issuer, false)
+	identities.identities = append(identities.identities, repository.CreateUserIdentity{UserID: 1, Issuer: issuer.URL, Subject: "sub-1"})
+
+	code := issuer.Code(issuer.Sign(issuer.Claims("sub-1", "n")))
+	user, err := s.Login(context.Background(), code, "v", "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 {
+		t.Errorf("got user %d, want 1", user.ID)
+	}
+}
+
+func TestOIDCLoginInvalidToken(t *testing.T) {
+	issuer := oidctest.NewIssuer("my-blog")
+	defer issuer.Close()
+	s, _ := newTestOIDCService(issuer, true)
+
+	code := issuer.Code(issuer.Sign(issuer.Claims("sub-1", "other")))
+	if _, err := s.Login(context.Background(), code, "v", "n"); err != ErrInvalidToken {
+		t.Errorf("got error %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestOIDCLoginWithoutAutoProvision(t *testing.T) {
+	issuer := oidctest.NewIssuer("my-blog")
+	defer issuer.Close()
+	s, identities := newTestOIDCService(issuer, false)
+
+	code := issuer.Code(issuer.Sign(issuer.Claims("sub-2", "n")))
+	if _, err := s.Login(context.Background(), code, "v", "n"); err != ErrIdentityNotLinked {
+		t.Errorf("got error %v, want ErrIdentityNotLinked", err)
+	}
+	if len(identities.provisioned) != 0 {
+		t.Errorf("provisioned %d users, want none", len(identities.provisioned))
+	}
+}
+
+func TestOIDCLoginAutoProvision(t *testing.T) {
+	issuer := oidctest.NewIssuer("my-blog")
+	defer issuer.Close()
+	s, identities := newTestOIDCService(issuer, true)
+
+	tests := []struct {
+		name         string
+		claims       map[string]interface{}
+		wantUsername string
+		wantEmail    string
+	}{
+		{
+			name:         "verified email",
+			claims:       map[string]interface{}{"preferred_username": "Bob", "email": "bob@example.com", "email_verified": true},
+			wantUsername: "bob",
+			wantEmail:    "bob@example.com",
+		},
+		{
+			name:         "unverified email is not kept",
+			claims:       map[string]interface{}{"email": "carol@example.com"},
+			wantUsername: "carol",
+		},
+		{
+			name:         "taken username and email",
+			claims:       map[string]interface{}{"preferred_username": "alice", "email": "alice@example.com", "email_verified": true},
+			wantUsername: "alice2",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := issuer.Claims(tt.name, "n")
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			user, err := s.Login(context.Background(), issuer.Code(issuer.Sign(claims)), "v", "n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(identities.provisioned) != i+1 {
+				t.Fatalf("provisioned %d users, want %d", len(identities.provisioned), i+1)
+			}
+			p := identities.provisioned[i]
+			if user.Username != tt.wantUsername || user.Email != tt.wantEmail || user.Role != auth.RoleAuthor {
+				t.Errorf("got user %q email %q role %q", user.Username, user.Email, user.Role)
+			}
+			if p.EmailVerifiedAt.Valid != (tt.wantEmail != "") {
+				t.Errorf("got email verified %v", p.EmailVerifiedAt.Valid)
+			}
+			if p.User.PasswordHash != externalPasswordHash {
+				t.Errorf("got password hash %q", p.User.PasswordHash)
+			}
+			if p.Identity.Issuer != issuer.URL || p.Identity.Subject != tt.name {
+				t.Errorf("got identity %s %s", p.Identity.Issuer, p.Identity.Subject)
+			}
+
+			// Signing in again finds the linked user.
+			again, err := s.Login(context.Background(), issuer.Code(issuer.Sign(claims)), "v", "n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.ID != user.ID || len(identities.provisioned) != i+1 {
+				t.Errorf("second sign in got user %d, provisioned %d", again.ID, len(identities.provisioned))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/password"
+	"sync"
+)
+
+const dummyPassword = "dummy password for unknown users"
+
+type PasswordsService struct {
+	usersRepo repository.UsersRepo
+	hasher    *password.Hasher
+	policy    *password.Policy
+	log       logger.Logger
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewPasswordsService(usersRepo repository.UsersRepo, cfg config.PasswordConfig, log logger.Logger) *PasswordsService {
+	argon := password.Argon2id{
+		Memory:      cfg.Argon2.Memory,
+		Iterations:  cfg.Argon2.Iterations,
+		Parallelism: cfg.Argon2.Parallelism,
+		SaltLength:  cfg.Argon2.SaltLength,
+		KeyLength:   cfg.Argon2.KeyLength,
+	}
+	bcrypt := password.Bcrypt{Cost: cfg.BcryptCost}
+	hasher := password.NewHasher(argon, bcrypt)
+	if cfg.Algorithm == bcrypt.Name() {
+		hasher = password.NewHasher(bcrypt, argon)
+	}
+
+	policy := password.NewPolicy(cfg.MinLength, cfg.MaxLength)
+	if cfg.BlocklistFile != "" {
+		if err := policy.LoadBlocklist(cfg.BlocklistFile); err != nil {
+			log.Errorf("error loading password blocklist: %s", err.Error())
+		}
+	}
+
+	return &PasswordsService{
+		usersRepo: usersRepo,
+		hasher:    hasher,
+		policy:    policy,
+		log:       log,
+	}
+}
+
+// Hash checks the password against the policy and hashes it with the
+// preferred algorithm.
+func (s *PasswordsService) Hash(plain, username string) (string, error) {
+	if err := s.policy.Check(plain, username); err != nil {
+		return "", err
+	}
+	return s.hasher.Hash(plain)
+}
+
+// Verify compares the password with the user's stored hash and upgrades
+// the hash when it was made with an old algorithm or weaker parameters.
+func (s *PasswordsService) Verify(ctx context.Context, user *domain.User, plain string) bool {
+	match, rehash, err := s.hasher.Verify(user.PasswordHash, plain)
+	if err != nil {
+		s.log.Errorf("error verifying password of user %d: %s", user.ID, err.Error())
+		return false
+	}
+	if match && rehash {
+		hashed, err := s.hasher.Hash(plain)
+		if err == nil {
+			err = s.usersRepo.UpdatePasswordHash(ctx, user.ID, hashed)
+		}
+		if err != nil {
+			s.log.Errorf("error rehashing password of user %d: %s", user.ID, err.Error())
+		} else {
+			user.PasswordHash = hashed
+		}
+	}
+	return match
+}
+
+// VerifyDummy spends as long as a real verification so response timing
+// does not reveal whether an account exists.
+func (s *PasswordsService) VerifyDummy(plain string) {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash(dummyPassword)
+	})
+	_, _, _ = s.hasher.Verify(s.dummyHash, plain)
+}
+
+func IsPasswordPolicyError(err error) bool {
+	switch err {
+	case password.ErrTooShort, password.ErrTooLong, password.ErrCommon, password.ErrContainsUsername, password.ErrPasswordTooLong:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+func authorize(ctx context.Context, p auth.Permission) error {
+	if !auth.FromContext(ctx).Can(p) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func canViewPost(u auth.User, post *domain.Post) bool {
+	return post.Status == domain.PostStatusPublished || post.UserID == u.ID || u.Can(auth.PermissionPostsModerate)
+}
+
+func canEditPost(u auth.User, post *domain.Post) bool {
+	if u.Can(auth.PermissionPostsModerate) {
+		return true
+	}
+	return post.UserID == u.ID && u.Can(auth.PermissionPostsWrite)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+)
+
+// GetMyPosts lists the posts of the current user whatever their status,
+// trashed ones aside, the last edited first. Editors may look at another
+// author's posts by filtering on them.
+func (p *PostsService) GetMyPosts(ctx context.Context, listing domain.PostListing) (*domain.PostDashboard, error) {
+	u := auth.FromContext(ctx)
+	if u.ID <= 0 {
+		return nil, ErrAccessDenied
+	}
+	criteria := repository.PostCriteria{UserID: u.ID}
+	if listing.Filter.Author != "" && u.Can(auth.PermissionPostsModerate) {
+		criteria.UserID = 0
+	}
+	if listing.Sort.Field == "" {
+		listing.Sort.Field = domain.PostSortUpdated
+	}
+	sort, err := listingCriteria(ctx, &criteria, listing.Filter, listing.Sort)
+	if err != nil {
+		return nil, err
+	}
+	page, err := p.postPage(ctx, criteria, sort, listing)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := p.postsRepo.CountPostsByStatus(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PostDashboard{
+		PostPage: page,
+		Counts: domain.PostStatusCounts{
+			Draft:     counts[domain.PostStatusDraft],
+			Scheduled: counts[domain.PostStatusScheduled],
+			Published: counts[domain.PostStatusPublished],
+			Trashed:   counts[domain.PostStatusDeleted],
+		},
+	}, nil
+}
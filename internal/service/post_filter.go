@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/slug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxFilterTags = 10
+
+var postFilterStatuses = map[string]int{
+	"draft":     domain.PostStatusDraft,
+	"scheduled": domain.PostStatusScheduled,
+	"published": domain.PostStatusPublished,
+	"trashed":   domain.PostStatusDeleted,
+}
+
+// listingCriteria narrows a listing down to the filter and sets its
+// order. Posts that aren't published are only listed to
+// their author, or to moderators, and default to the last edited first.
+func listingCriteria(ctx context.Context, criteria *repository.PostCriteria, filter domain.PostFilter, sort domain.PostSort) (domain.PostSort, error) {
+	verr := &ValidationError{}
+
+	if len(filter.Tags) > maxFilterTags {
+		verr.add("tags", fmt.Sprintf("must be at most %d tags", maxFilterTags))
+	}
+	seen := make(map[string]bool, len(filter.Tags))
+	for _, tag := range filter.Tags {
+		if !slug.IsValid(tag) {
+			verr.add("tags", fmt.Sprintf("%q is not a valid tag slug", tag))
+			continue
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			criteria.TagSlugs = append(criteria.TagSlugs, tag)
+		}
+	}
+	switch filter.TagsMatch {
+	case "", domain.TagsMatchAny:
+	case domain.TagsMatchAll:
+		criteria.AllTags = true
+	default:
+		verr.add("tags_match", "must be any or all")
+	}
+	criteria.Username = filter.Author
+
+	var err error
+	if filter.From != "" {
+		if criteria.PublishedFrom, err = parseFilterTime(filter.From, false); err != nil {
+			verr.add("from", "must be a date or an RFC 3339 time")
+		}
+	}
+	if filter.To != "" {
+		if criteria.PublishedTo, err = parseFilterTime(filter.To, true); err != nil {
+			verr.add("to", "must be a date or an RFC 3339 time")
+		}
+	}
+	if !criteria.PublishedFrom.IsZero() && !criteria.PublishedTo.IsZero() && !criteria.PublishedFrom.Before(criteria.PublishedTo) {
+		verr.add("to", "must be after from")
+	}
+
+	if filter.Status != "" {
+		status, ok := postFilterStatuses[filter.Status]
+		u := auth.FromContext(ctx)
+		switch {
+		case !ok:
+			verr.add("status", "must be one of draft, scheduled, published or trashed")
+		case status != domain.PostStatusPublished && u.ID <= 0:
+			verr.add("status", "sign in to list posts that are not published")
+		case status != domain.PostStatusPublished && !u.Can(auth.PermissionPostsModerate):
+			criteria.Status, criteria.UserID = status, u.ID
+		default:
+			criteria.Status = status
+		}
+	}
+	if criteria.Status != domain.PostStatusPublished {
+		switch sort.Field {
+		case "":
+			sort.Field = domain.PostSortUpdated
+		case domain.PostSortPublished:
+			verr.add("sort", "posts that are not published can't be sorted by publication date")
+		}
+	}
+
+	if filter.HasImage != "" {
+		if hasImage, err := strconv.ParseBool(filter.HasImage); err != nil {
+			verr.add("has_image", "must be true or false")
+		} else {
+			criteria.HasImage = &hasImage
+		}
+	}
+	if err := verr.errOrNil(); err != nil {
+		return sort, err
+	}
+	return sortCriteria(criteria, sort)
+}
+
+// parseFilterTime reads a date in local time or an RFC 3339 time. A date
+// given as an upper bound stands for the end of that day. A "+" offset
+// that wasn't percent-encoded arrives as a space and is read back as such.
+func parseFilterTime(s string, upper bool) (time.Time, error) {
+	s = strings.Replace(s, " ", "+", 1)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(time.Local), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("sort must be one of published, updated, title or popular")
+)
+
+// postCursor points between two posts of a listing, keyed on the sort
+// value and id of the post next to it. Prev cursors page back towards the
+// start of the listing.
+type postCursor struct {
+	Sort      string          `json:"s"`
+	Ascending bool            `json:"a,omitempty"`
+	Value     json.RawMessage `json:"v"`
+	ID        int             `json:"i"`
+	Prev      bool            `json:"p,omitempty"`
+}
+
+func newPostCursor(sort domain.PostSort, post *domain.Post, prev bool) string {
+	var value interface{}
+	switch sort.Field {
+	case domain.PostSortPublished:
+		value = post.PublishedAt
+	case domain.PostSortUpdated:
+		value = post.UpdatedAt
+	case domain.PostSortTitle:
+		value = post.Title
+	case domain.PostSortPopular:
+		value = post.Views
+	default:
+		value = post.CreatedAt
+	}
+	v, _ := json.Marshal(value)
+	b, _ := json.Marshal(postCursor{
+		Sort:      sort.Field,
+		Ascending: sort.Ascending,
+		Value:     v,
+		ID:        post.ID,
+		Prev:      prev,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parsePostCursor(s string) (postCursor, error) {
+	var c postCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// key decodes the sort value with the type of the column it came from.
+func (c postCursor) key() (interface{}, error) {
+	switch c.Sort {
+	case domain.PostSortTitle:
+		var title string
+		if err := json.Unmarshal(c.Value, &title); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return title, nil
+	case domain.PostSortPopular:
+		var views int64
+		if err := json.Unmarshal(c.Value, &views); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return views, nil
+	default:
+		var t time.Time
+		if err := json.Unmarshal(c.Value, &t); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	}
+}
+
+// sortCriteria sets the order of a listing, newest published first when
+// none is asked for.
+func sortCriteria(criteria *repository.PostCriteria, sort domain.PostSort) (domain.PostSort, error) {
+	switch sort.Field {
+	case "":
+		sort = domain.PostSort{Field: domain.PostSortPublished}
+	case domain.PostSortPublished, domain.PostSortUpdated, domain.PostSortTitle, domain.PostSortPopular:
+	default:
+		return sort, ErrInvalidSort
+	}
+	criteria.Sort, criteria.Ascending = sort.Field, sort.Ascending
+	return sort, nil
+}
+
+// ListPosts returns a page of posts matching the listing. Pages are keyed on the
+// last post seen rather than an offset, so posts published while a reader
+// is browsing don't shift the following pages.
+func (p *PostsService) ListPosts(ctx context.Context, listing domain.PostListing) (*domain.PostPage, error) {
+	criteria := repository.PostCriteria{
+		Status: domain.PostStatusPublished,
+		TagID:  listing.TagID,
+	}
+	sort, err := listingCriteria(ctx, &criteria, listing.Filter, listing.Sort)
+	if err != nil {
+		return nil, err
+	}
+	return p.postPage(ctx, criteria, sort, listing)
+}
+
+// postPage fetches the page of the listing picked by its cursor.
+func (p *PostsService) postPage(ctx context.Context, criteria repository.PostCriteria, sort domain.PostSort, listing domain.PostListing) (*domain.PostPage, error) {
+	var (
+		cursor postCursor
+		err    error
+	)
+	if listing.Cursor != "" {
+		if cursor, err = parsePostCursor(listing.Cursor); err != nil {
+			return nil, err
+		}
+		// A cursor only makes sense in the order it was handed out for.
+		if cursor.Sort != sort.Field || cursor.Ascending != sort.Ascending {
+			return nil, ErrInvalidCursor
+		}
+		if criteria.KeyValue, err = cursor.key(); err != nil {
+			return nil, err
+		}
+		criteria.KeyID, criteria.Backward = cursor.ID, cursor.Prev
+	}
+	// One extra post tells whether there is another page in the direction
+	// of travel.
+	criteria.Limit = listing.Limit + 1
+
+	posts, err := p.getPosts(ctx, criteria)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	page := &domain.PostPage{Items: make([]*domain.Post, 0)}
+	more := uint64(len(posts)) > listing.Limit
+	if more && cursor.Prev {
+		posts = posts[1:]
+	} else if more {
+		posts = posts[:listing.Limit]
+	}
+	if len(posts) > 0 {
+		page.Items = posts
+		first, last := posts[0], posts[len(posts)-1]
+		if (cursor.Prev && more) || (!cursor.Prev && listing.Cursor != "") {
+			page.Prev = newPostCursor(sort, first, true)
+		}
+		if (!cursor.Prev && more) || cursor.Prev {
+			page.Next = newPostCursor(sort, last, false)
+		}
+	}
+	if listing.WithTotal {
+		criteria.Limit = 0
+		total, err := p.postsRepo.CountPostsByCriteria(ctx, criteria)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
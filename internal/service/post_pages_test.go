@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+)
+
+func TestSortCriteria(t *testing.T) {
+	tests := []struct {
+		sort      domain.PostSort
+		wantField string
+		wantAsc   bool
+		wantErr   error
+	}{
+		{domain.PostSort{}, domain.PostSortPublished, false, nil},
+		{domain.PostSort{Field: domain.PostSortPublished, Ascending: true}, domain.PostSortPublished, true, nil},
+		{domain.PostSort{Field: domain.PostSortUpdated}, domain.PostSortUpdated, false, nil},
+		{domain.PostSort{Field: domain.PostSortTitle, Ascending: true}, domain.PostSortTitle, true, nil},
+		{domain.PostSort{Field: domain.PostSortPopular}, domain.PostSortPopular, false, nil},
+		{domain.PostSort{Field: domain.PostSortCreated}, "", false, ErrInvalidSort},
+		{domain.PostSort{Field: domain.PostSortScheduled}, "", false, ErrInvalidSort},
+		{domain.PostSort{Field: "random"}, "", false, ErrInvalidSort},
+	}
+	for _, tt := range tests {
+		var criteria repository.PostCriteria
+		sort, err := sortCriteria(&criteria, tt.sort)
+		if err != tt.wantErr {
+			t.Errorf("%+v: got error %v, want %v", tt.sort, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sort.Field != tt.wantField || sort.Ascending != tt.wantAsc {
+			t.Errorf("%+v: got sort %+v", tt.sort, sort)
+		}
+		if criteria.Sort != tt.wantField || criteria.Ascending != tt.wantAsc {
+			t.Errorf("%+v: got criteria sort %q ascending %v", tt.sort, criteria.Sort, criteria.Ascending)
+		}
+	}
+}
+
+func TestListingCriteriaSort(t *testing.T) {
+	author := auth.WithUser(context.Background(), auth.User{ID: 3, Role: auth.RoleAuthor})
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		status    int
+		filter    domain.PostFilter
+		sort      domain.PostSort
+		wantField string
+		wantErr   bool
+	}{
+		{"published listing", context.Background(), domain.PostStatusPublished, domain.PostFilter{}, domain.PostSort{}, domain.PostSortPublished, false},
+		{"drafts default to last edited", author, domain.PostStatusPublished, domain.PostFilter{Status: "draft"}, domain.PostSort{}, domain.PostSortUpdated, false},
+		{"dashboard defaults to last edited", author, 0, domain.PostFilter{}, domain.PostSort{}, domain.PostSortUpdated, false},
+		{"drafts by title", author, domain.PostStatusPublished, domain.PostFilter{Status: "draft"}, domain.PostSort{Field: domain.PostSortTitle}, domain.PostSortTitle, false},
+		{"drafts have no publication date", author, domain.PostStatusPublished, domain.PostFilter{Status: "draft"}, domain.PostSort{Field: domain.PostSortPublished}, "", true},
+	}
+	for _, tt := range tests {
+		criteria := repository.PostCriteria{Status: tt.status}
+		sort, err := listingCriteria(tt.ctx, &criteria, tt.filter, tt.sort)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if err == nil && (sort.Field != tt.wantField || criteria.Sort != tt.wantField) {
+			t.Errorf("%s: got sort %q, criteria sort %q, want %q", tt.name, sort.Field, criteria.Sort, tt.wantField)
+		}
+	}
+}
+
+func TestPostCursorKeys(t *testing.T) {
+	published := time.Date(2022, 8, 1, 10, 30, 15, 123456000, time.UTC)
+	updated := time.Date(2022, 8, 2, 9, 0, 0, 1000, time.UTC)
+	post := &domain.Post{
+		ID:          42,
+		Title:       "Ordering, \"stable\" and sound",
+		Views:       1234567,
+		PublishedAt: published,
+		UpdatedAt:   updated,
+	}
+	tests := []struct {
+		sort domain.PostSort
+		want interface{}
+	}{
+		{domain.PostSort{Field: domain.PostSortPublished}, published},
+		{domain.PostSort{Field: domain.PostSortPublished, Ascending: true}, published},
+		{domain.PostSort{Field: domain.PostSortUpdated}, updated},
+		{domain.PostSort{Field: domain.PostSortTitle, Ascending: true}, post.Title},
+		{domain.PostSort{Field: domain.PostSortPopular}, post.Views},
+	}
+	for _, tt := range tests {
+		for _, prev := range []bool{false, true} {
+			cursor, err := parsePostCursor(newPostCursor(tt.sort, post, prev))
+			if err != nil {
+				t.Fatalf("%+v: %v", tt.sort, err)
+			}
+			if cursor.Sort != tt.sort.Field || cursor.Ascending != tt.sort.Ascending || cursor.Prev != prev || cursor.ID != post.ID {
+				t.Errorf("%+v: got cursor %+v", tt.sort, cursor)
+			}
+			key, err := cursor.key()
+			if err != nil {
+				t.Fatalf("%+v: %v", tt.sort, err)
+			}
+			switch want := tt.want.(type) {
+			case time.Time:
+				if got, ok := key.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("%+v: got key %v, want %v", tt.sort, key, want)
+				}
+			default:
+				if key != want {
+					t.Errorf("%+v: got key %#v, want %#v", tt.sort, key, want)
+				}
+			}
+		}
+	}
+}
+
+func TestParsePostCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"%%%", "bm90IGpzb24", "eyJpIjowfQ"} {
+		if _, err := parsePostCursor(s); err != ErrInvalidCursor {
+			t.Errorf("%q: got error %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
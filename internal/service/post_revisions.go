@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/diff"
+	"strconv"
+	"strings"
+)
+
+// RevisionCurrent refers to the live version of a post when diffing.
+const RevisionCurrent = "current"
+
+var ErrInvalidRevision = errors.New("revision must be a revision id or \"current\"")
+
+func (p *PostsService) GetRevisions(ctx context.Context, postID int) ([]*domain.PostRevision, error) {
+	if _, err := p.editablePost(ctx, postID); err != nil {
+		return nil, err
+	}
+	dbRevisions, err := p.revisionsRepo.GetRevisions(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.PostRevision, 0, len(dbRevisions))
+	for _, r := range dbRevisions {
+		revision, err := toDomainRevision(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, revision)
+	}
+	return out, nil
+}
+
+func (p *PostsService) GetRevision(ctx context.Context, postID, revisionID int) (*domain.PostRevision, error) {
+	if _, err := p.editablePost(ctx, postID); err != nil {
+		return nil, err
+	}
+	return p.getRevision(ctx, postID, revisionID)
+}
+
+// DiffRevisions returns a unified diff between two versions of a post, each
+// given as a revision id or RevisionCurrent.
+func (p *PostsService) DiffRevisions(ctx context.Context, postID int, from, to string) (*domain.RevisionDiff, error) {
+	current, err := p.editablePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	fromText, err := p.revisionText(ctx, current, from)
+	if err != nil {
+		return nil, err
+	}
+	toText, err := p.revisionText(ctx, current, to)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.RevisionDiff{
+		PostID: postID,
+		From:   from,
+		To:     to,
+		Diff:   diff.Unified(revisionName(from), revisionName(to), fromText, toText),
+	}, nil
+}
+
+// RestoreRevision brings back the title, subtitle, image, content and tags
+// of a revision as a new update, so the version it replaces is kept as a
+// revision too. Status and slug stay as they are.
+func (p *PostsService) RestoreRevision(ctx context.Context, postID, revisionID int) error {
+	current, err := p.editablePost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	revision, err := p.getRevision(ctx, postID, revisionID)
+	if err != nil {
+		return err
+	}
+	tagIDs := make([]int, 0, len(revision.Tags))
+	for _, t := range revision.Tags {
+		if _, err := p.tagsRepo.GetTagByID(ctx, t.ID); err == nil {
+			tagIDs = append(tagIDs, t.ID)
+		}
+	}
+	updatePost := domain.UpdatePost{
+		ID:       postID,
+		Status:   current.Status,
+		Title:    revision.Title,
+		Subtitle: revision.Subtitle,
+		ImageURL: revision.ImageURL,
+		Content:  revision.Content,
+		Format:   revision.Format,
+		Slug:     current.Slug,
+		TagIDs:   tagIDs,
+	}
+	if current.ManualReadingTime {
+		updatePost.ReadingTime = current.ReadingTime
+	}
+	if current.ManualExcerpt {
+		updatePost.Excerpt = current.Excerpt
+	}
+	return p.UpdatePost(ctx, updatePost)
+}
+
+func (p *PostsService) getRevision(ctx context.Context, postID, revisionID int) (*domain.PostRevision, error) {
+	dbRevision, err := p.revisionsRepo.GetRevision(ctx, postID, revisionID)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomainRevision(dbRevision)
+}
+
+func (p *PostsService) revisionText(ctx context.Context, current *domain.Post, ref string) (string, error) {
+	if ref == RevisionCurrent {
+		return postText(current.Status, current.Title, current.Subtitle, current.ImageURL, current.Slug,
+			current.Format, current.Tags, current.Content), nil
+	}
+	id, err := strconv.Atoi(ref)
+	if err != nil || id <= 0 {
+		return "", ErrInvalidRevision
+	}
+	r, err := p.getRevision(ctx, current.ID, id)
+	if err != nil {
+		return "", err
+	}
+	return postText(r.Status, r.Title, r.Subtitle, r.ImageURL, r.Slug, r.Format, r.Tags, r.Content), nil
+}
+
+func revisionName(ref string) string {
+	if ref == RevisionCurrent {
+		return ref
+	}
+	return "revision " + ref
+}
+
+// postText lays a version of a post out as text, metadata first, so that
+// versions can be compared line by line.
+func postText(status int, title, subtitle, imageURL, slug, format string, tags []*domain.Tag, content string) string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "title: %s\n", title)
+	fmt.Fprintf(&b, "subtitle: %s\n", subtitle)
+	fmt.Fprintf(&b, "image_url: %s\n", imageURL)
+	fmt.Fprintf(&b, "slug: %s\n", slug)
+	fmt.Fprintf(&b, "status: %s\n", statusName(status))
+	fmt.Fprintf(&b, "format: %s\n", format)
+	fmt.Fprintf(&b, "tags: %s\n", strings.Join(names, ", "))
+	b.WriteString("\n")
+	b.WriteString(content)
+	return b.String()
+}
+
+func statusName(status int) string {
+	switch status {
+	case domain.PostStatusDraft:
+		return "draft"
+	case domain.PostStatusScheduled:
+		return "scheduled"
+	case domain.PostStatusPublished:
+		return "published"
+	case domain.PostStatusDeleted:
+		return "deleted"
+	default:
+		return strconv.Itoa(status)
+	}
+}
+
+func toDomainRevision(r *repository.PostRevision) (*domain.PostRevision, error) {
+	tags := make([]*domain.Tag, 0)
+	if len(r.Tags) > 0 {
+		if err := json.Unmarshal(r.Tags, &tags); err != nil {
+			return nil, err
+		}
+	}
+	return &domain.PostRevision{
+		ID:             r.ID,
+		PostID:         r.PostID,
+		EditorID:       int(r.EditorID.Int32),
+		EditorUsername: r.EditorUsername.String,
+		Status:         r.Status,
+		Title:          r.Title,
+		Subtitle:       r.Subtitle,
+		ImageURL:       r.ImageURL,
+		Content:        r.Content,
+		Format:         r.Format,
+		Slug:           r.Slug,
+		Tags:           tags,
+		EditedAt:       r.EditedAt,
+		CreatedAt:      r.CreatedAt,
+	}, nil
+}
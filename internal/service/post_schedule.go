@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const maxScheduledPosts = 1000
+
+var (
+	ErrNotSchedulable  = errors.New("only drafts and scheduled posts can be scheduled")
+	ErrNotScheduled    = errors.New("post is not scheduled")
+	ErrPublishAtInPast = errors.New("publish_at must be in the future")
+)
+
+// SchedulePost schedules a draft for publishing at publishAt, or moves the
+// publishing time of a post that is already scheduled.
+func (p *PostsService) SchedulePost(ctx context.Context, postID int, publishAt time.Time) error {
+	current, err := p.editablePost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if current.Status != domain.PostStatusDraft && current.Status != domain.PostStatusScheduled {
+		return ErrNotSchedulable
+	}
+	if !publishAt.After(time.Now()) {
+		return ErrPublishAtInPast
+	}
+	return p.postsRepo.UpdatePostSchedule(ctx, repository.PostSchedule{
+		ID:        postID,
+		Status:    domain.PostStatusScheduled,
+		PublishAt: sql.NullTime{Time: publishAt.Local(), Valid: true},
+		UpdatedAt: time.Now(),
+	})
+}
+
+// CancelSchedule turns a scheduled post back into a draft.
+func (p *PostsService) CancelSchedule(ctx context.Context, postID int) error {
+	current, err := p.editablePost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if current.Status != domain.PostStatusScheduled {
+		return ErrNotScheduled
+	}
+	return p.postsRepo.UpdatePostSchedule(ctx, repository.PostSchedule{
+		ID:        postID,
+		Status:    domain.PostStatusDraft,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// GetScheduledPosts lists the posts of the current user that are waiting to
+// be published, the next one first.
+func (p *PostsService) GetScheduledPosts(ctx context.Context) ([]*domain.Post, error) {
+	u := auth.FromContext(ctx)
+	return p.getPosts(ctx, repository.PostCriteria{
+		UserID:    u.ID,
+		Status:    domain.PostStatusScheduled,
+		Sort:      domain.PostSortScheduled,
+		Ascending: true,
+		Limit:     maxScheduledPosts,
+	})
+}
+
+// Publisher publishes scheduled posts once they are due. Every app instance
+// may run one; the repository makes sure a post is published only once.
+type Publisher struct {
+	postsRepo repository.PostsRepo
+	interval  time.Duration
+	batchSize int
+	log       logger.Logger
+}
+
+func NewPublisher(postsRepo repository.PostsRepo, interval time.Duration, batchSize int, log logger.Logger) *Publisher {
+	return &Publisher{
+		postsRepo: postsRepo,
+		interval:  interval,
+		batchSize: batchSize,
+		log:       log,
+	}
+}
+
+// Run publishes due posts every interval until ctx is done. A zero interval
+// disables the publisher.
+func (p *Publisher) Run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.PublishDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes every post that is due, a batch at a time.
+func (p *Publisher) PublishDue(ctx context.Context) {
+	for {
+		ids, err := p.postsRepo.PublishDuePosts(ctx, time.Now(), p.batchSize)
+		if err != nil {
+			p.log.Errorf("error publishing scheduled posts: %s", err.Error())
+			return
+		}
+		for _, id := range ids {
+			p.log.Infof("published scheduled post %d", id)
+		}
+		if len(ids) == 0 || len(ids) < p.batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/search"
+)
+
+var ErrEmptyQuery = search.ErrEmptyQuery
+
+// SearchPosts runs a full-text search, best matches first. Anonymous
+// readers only find published posts, authors also find their own drafts.
+func (p *PostsService) SearchPosts(ctx context.Context, postSearch domain.PostSearch) ([]*domain.PostSearchResult, error) {
+	query, err := search.ParseQuery(postSearch.Query)
+	if err != nil {
+		return nil, err
+	}
+	u := auth.FromContext(ctx)
+	criteria := repository.PostSearch{
+		Query:       query,
+		Language:    p.searchLanguage,
+		AuthorID:    postSearch.AuthorID,
+		TagSlug:     postSearch.TagSlug,
+		AllStatuses: u.Can(auth.PermissionPostsModerate),
+		Limit:       postSearch.Limit,
+		Offset:      postSearch.Offset,
+	}
+	if u.ID > 0 {
+		criteria.ViewerID = u.ID
+	}
+	matches, err := p.postsRepo.SearchPosts(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, ErrNotFound
+	}
+
+	ids := make(pq.Int64Array, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, int64(m.ID))
+	}
+	posts, err := p.getPosts(ctx, repository.PostCriteria{IDs: ids, Limit: uint64(len(ids))})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*domain.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	out := make([]*domain.PostSearchResult, 0, len(matches))
+	for _, m := range matches {
+		if post, ok := byID[m.ID]; ok {
+			out = append(out, &domain.PostSearchResult{Post: post, Rank: m.Rank, Snippet: m.Snippet})
+		}
+	}
+	return out, nil
+}
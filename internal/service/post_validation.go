@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"github.com/scraletteykt/my-blog/pkg/render"
+	"github.com/scraletteykt/my-blog/pkg/slug"
+	"html"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength    = 255
+	maxSubtitleLength = 1000
+	maxSlugLength     = 255
+	maxExcerptLength  = 1000
+	maxContentBytes   = 1 << 20
+)
+
+type postFields struct {
+	Title       string
+	Subtitle    string
+	Excerpt     string
+	ReadingTime int
+	ImageURL    string
+	Content     string
+	Format      string
+	Slug        string
+}
+
+// cleanPost validates the user-submitted fields of a post and sanitizes
+// HTML content before it is stored.
+func (p *PostsService) cleanPost(f *postFields) error {
+	v := &ValidationError{}
+	switch {
+	case f.Title == "":
+		v.add("title", "title is required")
+	case utf8.RuneCountInString(f.Title) > maxTitleLength:
+		v.add("title", fmt.Sprintf("title must be at most %d characters", maxTitleLength))
+	case p.hasMarkup(f.Title):
+		v.add("title", "title must not contain HTML")
+	}
+	switch {
+	case utf8.RuneCountInString(f.Subtitle) > maxSubtitleLength:
+		v.add("subtitle", fmt.Sprintf("subtitle must be at most %d characters", maxSubtitleLength))
+	case p.hasMarkup(f.Subtitle):
+		v.add("subtitle", "subtitle must not contain HTML")
+	}
+	switch {
+	case utf8.RuneCountInString(f.Excerpt) > maxExcerptLength:
+		v.add("excerpt", fmt.Sprintf("excerpt must be at most %d characters", maxExcerptLength))
+	case p.hasMarkup(f.Excerpt):
+		v.add("excerpt", "excerpt must not contain HTML")
+	}
+	if f.ReadingTime < 0 {
+		v.add("reading_time", "reading time must not be negative")
+	}
+	if f.ImageURL != "" && !isWebURL(f.ImageURL) {
+		v.add("image_url", ErrInvalidURL.Error())
+	}
+	if len(f.Content) > maxContentBytes {
+		v.add("content", fmt.Sprintf("content must be at most %d bytes", maxContentBytes))
+	}
+	if !render.IsValidFormat(f.Format) {
+		v.add("format", render.ErrInvalidFormat.Error())
+	}
+	switch {
+	case utf8.RuneCountInString(f.Slug) > maxSlugLength:
+		v.add("slug", fmt.Sprintf("slug must be at most %d characters", maxSlugLength))
+	case f.Slug != "" && !slug.IsValid(f.Slug):
+		v.add("slug", ErrInvalidSlug.Error())
+	}
+	if err := v.errOrNil(); err != nil {
+		return err
+	}
+	if f.Format == render.FormatHTML {
+		f.Content = p.sanitizer.HTML(f.Content)
+	}
+	return nil
+}
+
+func (p *PostsService) hasMarkup(s string) bool {
+	return html.UnescapeString(p.sanitizer.Text(s)) != s
+}
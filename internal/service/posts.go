@@ -8,24 +8,36 @@ import (
 	"github.com/scraletteykt/my-blog/internal/repository"
 	"github.com/scraletteykt/my-blog/pkg/auth"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/render"
+	"github.com/scraletteykt/my-blog/pkg/sanitize"
 	"time"
 )
 
 var ErrNotFound = errors.New("not found rows in result set")
 
 type PostsService struct {
-	postsRepo     repository.PostsRepo
-	tagsRepo      repository.TagsRepo
-	postsTagsRepo repository.PostsTagsRepo
-	log           logger.Logger
+	postsRepo      repository.PostsRepo
+	tagsRepo       repository.TagsRepo
+	redirectsRepo  repository.SlugRedirectsRepo
+	revisionsRepo  repository.PostRevisionsRepo
+	renderer       *render.Renderer
+	sanitizer      *sanitize.Sanitizer
+	stats          render.StatsConfig
+	searchLanguage string
+	log            logger.Logger
 }
 
-func NewPostsService(postsRepo repository.PostsRepo, tagsRepo repository.TagsRepo, postsTagsRepo repository.PostsTagsRepo, log logger.Logger) *PostsService {
+func NewPostsService(postsRepo repository.PostsRepo, tagsRepo repository.TagsRepo, redirectsRepo repository.SlugRedirectsRepo, revisionsRepo repository.PostRevisionsRepo, renderer *render.Renderer, sanitizer *sanitize.Sanitizer, stats render.StatsConfig, searchLanguage string, log logger.Logger) *PostsService {
 	return &PostsService{
-		postsRepo:     postsRepo,
-		tagsRepo:      tagsRepo,
-		postsTagsRepo: postsTagsRepo,
-		log:           log,
+		postsRepo:      postsRepo,
+		tagsRepo:       tagsRepo,
+		redirectsRepo:  redirectsRepo,
+		revisionsRepo:  revisionsRepo,
+		renderer:       renderer,
+		sanitizer:      sanitizer,
+		stats:          stats,
+		searchLanguage: searchLanguage,
+		log:            log,
 	}
 }
 
@@ -43,102 +55,233 @@ func (p *PostsService) GetPostByID(ctx context.Context, id int) (*domain.Post, e
 		return nil, err
 	}
 	post := posts[0]
-	if post.UserID != u.ID && post.Status != domain.PostStatusPublished {
+	if !canViewPost(u, post) {
 		return nil, ErrNotFound
 	}
 	return posts[0], nil
 }
 
-func (p *PostsService) GetPosts(ctx context.Context, limit, offset uint64) ([]*domain.Post, error) {
-	return p.getPosts(ctx, repository.PostCriteria{
+// GetPostBySlug looks a post up by its slug. A previous slug of a post
+// yields a SlugMovedError carrying the current one.
+func (p *PostsService) GetPostBySlug(ctx context.Context, slug string) (*domain.Post, error) {
+	posts, err := p.getPosts(ctx, repository.PostCriteria{Slug: slug})
+	if err == ErrNotFound {
+		return nil, p.movedPost(ctx, slug)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canViewPost(auth.FromContext(ctx), posts[0]) {
+		return nil, ErrNotFound
+	}
+	return posts[0], nil
+}
+
+// CountPostView counts a reader of a published post towards its
+// popularity, leaving out the author.
+func (p *PostsService) CountPostView(ctx context.Context, post *domain.Post) {
+	if post.Status != domain.PostStatusPublished || auth.FromContext(ctx).ID == post.UserID {
+		return
+	}
+	if err := p.postsRepo.IncrementPostViews(ctx, post.ID); err != nil {
+		p.log.Errorf("error counting view of post %d: %s", post.ID, err.Error())
+	}
+}
+
+func (p *PostsService) movedPost(ctx context.Context, slug string) error {
+	postID, err := redirectTarget(ctx, p.redirectsRepo, domain.RedirectKindPost, slug)
+	if err != nil {
+		return err
+	}
+	post, err := p.GetPostByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	return &SlugMovedError{Slug: post.Slug}
+}
+
+func (p *PostsService) GetPosts(ctx context.Context, filter domain.PostFilter, sort domain.PostSort, limit, offset uint64) ([]*domain.Post, error) {
+	criteria := repository.PostCriteria{
 		ID:     0,
 		UserID: 0,
 		Status: domain.PostStatusPublished,
 		TagID:  0,
 		Limit:  limit,
 		Offset: offset,
-	})
+	}
+	if _, err := listingCriteria(ctx, &criteria, filter, sort); err != nil {
+		return nil, err
+	}
+	return p.getPosts(ctx, criteria)
 }
 
-func (p *PostsService) GetPostsByTag(ctx context.Context, tagID int, limit, offset uint64) ([]*domain.Post, error) {
-	return p.getPosts(ctx, repository.PostCriteria{
+func (p *PostsService) GetPostsByTag(ctx context.Context, tagID int, filter domain.PostFilter, sort domain.PostSort, limit, offset uint64) ([]*domain.Post, error) {
+	criteria := repository.PostCriteria{
 		ID:     0,
 		UserID: 0,
 		Status: domain.PostStatusPublished,
 		TagID:  tagID,
 		Limit:  limit,
 		Offset: offset,
-	})
+	}
+	if _, err := listingCriteria(ctx, &criteria, filter, sort); err != nil {
+		return nil, err
+	}
+	return p.getPosts(ctx, criteria)
 }
 
-func (p *PostsService) GetPostsByUser(ctx context.Context, userID int, limit, offset uint64) ([]*domain.Post, error) {
-	return p.getPosts(ctx, repository.PostCriteria{
+func (p *PostsService) GetPostsByUser(ctx context.Context, userID int, sort domain.PostSort, limit, offset uint64) ([]*domain.Post, error) {
+	status := domain.PostStatusPublished
+	if u := auth.FromContext(ctx); u.ID == userID || u.Can(auth.PermissionPostsModerate) {
+		status = 0
+	}
+	criteria := repository.PostCriteria{
 		ID:     0,
 		UserID: userID,
-		Status: 0,
+		Status: status,
 		TagID:  0,
 		Limit:  limit,
 		Offset: offset,
-	})
+	}
+	if _, err := sortCriteria(&criteria, sort); err != nil {
+		return nil, err
+	}
+	return p.getPosts(ctx, criteria)
 }
 
 func (p *PostsService) CreatePost(ctx context.Context, createPost domain.CreatePost) error {
-	postID, err := p.postsRepo.CreatePost(ctx, repository.CreatePost{
-		UserID:      createPost.UserID,
-		ReadingTime: createPost.ReadingTime,
-		Status:      domain.PostStatusDraft,
+	if err := authorize(ctx, auth.PermissionPostsWrite); err != nil {
+		return err
+	}
+	if createPost.Format == "" {
+		createPost.Format = render.FormatMarkdown
+	}
+	fields := postFields{
 		Title:       createPost.Title,
 		Subtitle:    createPost.Subtitle,
+		Excerpt:     createPost.Excerpt,
+		ReadingTime: createPost.ReadingTime,
 		ImageURL:    createPost.ImageURL,
 		Content:     createPost.Content,
-		Slug:        createPost.Content,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	})
+		Format:      createPost.Format,
+		Slug:        createPost.Slug,
+	}
+	if err := p.cleanPost(&fields); err != nil {
+		return err
+	}
+	postSlug, err := p.resolveSlug(ctx, createPost.Slug, createPost.Title, 0)
 	if err != nil {
 		return err
 	}
-	for _, tagID := range createPost.TagIDs {
-		err := p.postsTagsRepo.TagPost(ctx, tagID, postID)
-		if err != nil {
-			continue
-		}
+	createPost.Content = fields.Content
+	contentHTML, err := p.renderer.Render(createPost.Format, createPost.Content)
+	if err != nil {
+		return err
 	}
-	return nil
+	stats := p.postStats(contentHTML, createPost.ReadingTime, createPost.Excerpt)
+	_, err = p.postsRepo.CreatePost(ctx, repository.CreatePost{
+		UserID:        createPost.UserID,
+		ReadingTime:   stats.ReadingTime,
+		WordCount:     stats.WordCount,
+		Excerpt:       stats.Excerpt,
+		ManualReading: createPost.ReadingTime > 0,
+		ManualExcerpt: createPost.Excerpt != "",
+		Status:        domain.PostStatusDraft,
+		Title:         createPost.Title,
+		Subtitle:      createPost.Subtitle,
+		ImageURL:      createPost.ImageURL,
+		Content:       createPost.Content,
+		Format:        createPost.Format,
+		ContentHTML:   contentHTML,
+		RenderVersion: render.Version,
+		Slug:          postSlug,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     createPost.UserID,
+		SearchConfig:  p.searchLanguage,
+		TagIDs:        createPost.TagIDs,
+	})
+	// The slug may have been taken since it was resolved.
+	if err == repository.ErrSlugTaken {
+		return slugTakenError()
+	}
+	return err
 }
 
 func (p *PostsService) UpdatePost(ctx context.Context, updatePost domain.UpdatePost) error {
-	var publishedAt sql.NullTime
-	if updatePost.Status == domain.PostStatusPublished {
-		publishedAt.Time = time.Now()
-		publishedAt.Valid = true
-	} else {
-		publishedAt.Time = time.Time{}
-		publishedAt.Valid = false
-	}
-	err := p.postsRepo.UpdatePost(ctx, repository.UpdatePost{
-		ID:          updatePost.ID,
-		ReadingTime: updatePost.ReadingTime,
-		Status:      updatePost.Status,
+	current, err := p.editablePost(ctx, updatePost.ID)
+	if err != nil {
+		return err
+	}
+	fields := postFields{
 		Title:       updatePost.Title,
 		Subtitle:    updatePost.Subtitle,
+		Excerpt:     updatePost.Excerpt,
+		ReadingTime: updatePost.ReadingTime,
 		ImageURL:    updatePost.ImageURL,
 		Content:     updatePost.Content,
+		Format:      updatePost.Format,
 		Slug:        updatePost.Slug,
-		PublishedAt: publishedAt,
-		UpdatedAt:   time.Now(),
-	})
+	}
+	if err := p.cleanPost(&fields); err != nil {
+		return err
+	}
+	postSlug, err := p.resolveSlug(ctx, updatePost.Slug, updatePost.Title, updatePost.ID)
 	if err != nil {
 		return err
 	}
-	err = p.postsTagsRepo.UpdatePostTags(ctx, updatePost.TagIDs, updatePost.ID)
+	updatePost.Content = fields.Content
+	contentHTML, err := p.renderer.Render(updatePost.Format, updatePost.Content)
 	if err != nil {
 		return err
 	}
-	return nil
+	stats := p.postStats(contentHTML, updatePost.ReadingTime, updatePost.Excerpt)
+	var publishedAt, publishAt sql.NullTime
+	switch updatePost.Status {
+	case domain.PostStatusPublished:
+		publishedAt.Time, publishedAt.Valid = time.Now(), true
+		if current.Status == domain.PostStatusPublished && !current.PublishedAt.IsZero() {
+			publishedAt.Time = current.PublishedAt
+		}
+	case domain.PostStatusScheduled:
+		if current.Status != domain.PostStatusScheduled {
+			return ErrNotScheduled
+		}
+		publishAt.Time, publishAt.Valid = current.PublishAt, true
+	}
+	err = p.postsRepo.UpdatePost(ctx, repository.UpdatePost{
+		ID:            updatePost.ID,
+		ReadingTime:   stats.ReadingTime,
+		WordCount:     stats.WordCount,
+		Excerpt:       stats.Excerpt,
+		ManualReading: updatePost.ReadingTime > 0,
+		ManualExcerpt: updatePost.Excerpt != "",
+		Status:        updatePost.Status,
+		Title:         updatePost.Title,
+		Subtitle:      updatePost.Subtitle,
+		ImageURL:      updatePost.ImageURL,
+		Content:       updatePost.Content,
+		Format:        updatePost.Format,
+		ContentHTML:   contentHTML,
+		RenderVersion: render.Version,
+		Slug:          postSlug,
+		PublishedAt:   publishedAt,
+		PublishAt:     publishAt,
+		UpdatedAt:     time.Now(),
+		UpdatedBy:     auth.FromContext(ctx).ID,
+		PreviousSlug:  current.Slug,
+		TagIDs:        updatePost.TagIDs,
+	})
+	if err == repository.ErrSlugTaken {
+		return slugTakenError()
+	}
+	return err
 }
 
 func (p *PostsService) DeletePost(ctx context.Context, deletePost domain.DeletePost) error {
+	if _, err := p.editablePost(ctx, deletePost.ID); err != nil {
+		return err
+	}
 	err := p.postsRepo.DeletePost(ctx, repository.DeletePost{
 		ID:        deletePost.ID,
 		Status:    domain.PostStatusDeleted,
@@ -150,6 +293,23 @@ func (p *PostsService) DeletePost(ctx context.Context, deletePost domain.DeleteP
 	return nil
 }
 
+func (p *PostsService) resolveSlug(ctx context.Context, requested, title string, postID int) (string, error) {
+	return resolveSlug(requested, title, "post", func(base string) ([]string, error) {
+		return p.postsRepo.GetSlugsLike(ctx, base, postID)
+	})
+}
+
+func (p *PostsService) editablePost(ctx context.Context, postID int) (*domain.Post, error) {
+	posts, err := p.getPosts(ctx, repository.PostCriteria{ID: postID})
+	if err != nil {
+		return nil, err
+	}
+	if !canEditPost(auth.FromContext(ctx), posts[0]) {
+		return nil, ErrAccessDenied
+	}
+	return posts[0], nil
+}
+
 func (p *PostsService) getPosts(ctx context.Context, criteria repository.PostCriteria) ([]*domain.Post, error) {
 	dbPosts, err := p.postsRepo.GetPostsByCriteria(ctx, criteria)
 	if err != nil {
@@ -166,44 +326,112 @@ func (p *PostsService) getPosts(ctx context.Context, criteria repository.PostCri
 	}
 
 	posts := make([]*domain.Post, 0)
-
 	for _, dbPost := range dbPosts {
-		var (
-			publishedAt time.Time
-			deletedAt   time.Time
-		)
-		if dbPost.PublishedAt.Valid {
-			publishedAt = dbPost.PublishedAt.Time
+		if dbPost.RenderVersion != render.Version {
+			p.rerender(ctx, dbPost)
 		}
-		if dbPost.DeletedAt.Valid {
-			deletedAt = dbPost.DeletedAt.Time
-		}
-		tags := make([]*domain.Tag, 0)
-		pst := &domain.Post{
-			ID:          dbPost.ID,
-			UserID:      dbPost.UserID,
-			ReadingTime: dbPost.ReadingTime,
-			Status:      dbPost.Status,
-			Title:       dbPost.Title,
-			Subtitle:    dbPost.Subtitle,
-			ImageURL:    dbPost.ImageURL,
-			Content:     dbPost.Content,
-			Slug:        dbPost.Slug,
-			PublishedAt: publishedAt,
-			CreatedAt:   dbPost.CreatedAt,
-			UpdatedAt:   dbPost.UpdatedAt,
-			DeletedAt:   deletedAt,
-			Tags:        tags,
+		posts = append(posts, toDomainPost(dbPost))
+	}
+	return posts, nil
+}
+
+// rerender refreshes HTML and stats cached by an older rendering pipeline.
+func (p *PostsService) rerender(ctx context.Context, dbPost *repository.Post) {
+	contentHTML, err := p.renderer.Render(dbPost.Format, dbPost.Content)
+	if err != nil {
+		p.log.Errorf("error rendering post %d: %s", dbPost.ID, err.Error())
+		return
+	}
+	stats := render.Analyze(contentHTML, p.stats)
+	dbPost.ContentHTML, dbPost.RenderVersion, dbPost.WordCount = contentHTML, render.Version, stats.WordCount
+	if !dbPost.ManualReading {
+		dbPost.ReadingTime = stats.ReadingTime
+	}
+	if !dbPost.ManualExcerpt {
+		dbPost.Excerpt = stats.Excerpt
+	}
+	err = p.postsRepo.UpdatePostRendering(ctx, repository.PostRendering{
+		ID:            dbPost.ID,
+		ContentHTML:   dbPost.ContentHTML,
+		RenderVersion: dbPost.RenderVersion,
+		ReadingTime:   dbPost.ReadingTime,
+		WordCount:     dbPost.WordCount,
+		Excerpt:       dbPost.Excerpt,
+	})
+	if err != nil {
+		p.log.Errorf("error caching rendered post %d: %s", dbPost.ID, err.Error())
+	}
+}
+
+// postStats computes the values derived from the content, keeping the ones
+// the author set explicitly.
+func (p *PostsService) postStats(contentHTML string, readingTime int, excerpt string) render.Stats {
+	stats := render.Analyze(contentHTML, p.stats)
+	if readingTime > 0 {
+		stats.ReadingTime = readingTime
+	}
+	if excerpt != "" {
+		stats.Excerpt = excerpt
+	}
+	return stats
+}
+
+func toDomainPost(dbPost *repository.Post) *domain.Post {
+	var (
+		publishedAt time.Time
+		publishAt   time.Time
+		deletedAt   time.Time
+	)
+	if dbPost.PublishedAt.Valid {
+		publishedAt = dbPost.PublishedAt.Time
+	}
+	if dbPost.PublishAt.Valid {
+		publishAt = dbPost.PublishAt.Time
+	}
+	if dbPost.DeletedAt.Valid {
+		deletedAt = dbPost.DeletedAt.Time
+	}
+	tags := make([]*domain.Tag, 0)
+	pst := &domain.Post{
+		ID:          dbPost.ID,
+		UserID:      dbPost.UserID,
+		ReadingTime: dbPost.ReadingTime,
+		WordCount:   dbPost.WordCount,
+		Excerpt:     dbPost.Excerpt,
+		Status:      dbPost.Status,
+		Title:       dbPost.Title,
+		Subtitle:    dbPost.Subtitle,
+		ImageURL:    dbPost.ImageURL,
+		Content:     dbPost.Content,
+		Format:      dbPost.Format,
+		ContentHTML: dbPost.ContentHTML,
+		Slug:        dbPost.Slug,
+		Views:       dbPost.Views,
+		PublishedAt: publishedAt,
+		PublishAt:   publishAt,
+		CreatedAt:   dbPost.CreatedAt,
+		UpdatedAt:   dbPost.UpdatedAt,
+		DeletedAt:   deletedAt,
+		Tags:        tags,
+
+		ManualReadingTime: dbPost.ManualReading,
+		ManualExcerpt:     dbPost.ManualExcerpt,
+	}
+	if dbPost.Author != nil {
+		pst.Author = &domain.Author{
+			ID:          dbPost.UserID,
+			Username:    dbPost.Author.Username,
+			DisplayName: dbPost.Author.DisplayName,
+			AvatarURL:   dbPost.Author.AvatarURL,
 		}
-		for _, dbTag := range dbPost.Tags {
-			t := &domain.Tag{
-				ID:   dbTag.ID,
-				Name: dbTag.Name,
-				Slug: dbTag.Slug,
-			}
-			pst.Tags = append(pst.Tags, t)
+	}
+	for _, dbTag := range dbPost.Tags {
+		t := &domain.Tag{
+			ID:   dbTag.ID,
+			Name: dbTag.Name,
+			Slug: dbTag.Slug,
 		}
-		posts = append(posts, pst)
+		pst.Tags = append(pst.Tags, t)
 	}
-	return posts, nil
+	return pst
 }
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength = 100
+	maxBioLength         = 2000
+	maxURLLength         = 2048
+	maxSocialLinks       = 10
+	maxSocialNameLength  = 32
+)
+
+var (
+	ErrInvalidURL = errors.New("urls must be absolute http or https links")
+)
+
+func (s *UsersService) GetProfile(ctx context.Context, username string) (*domain.Profile, error) {
+	profileDB, err := s.repo.GetProfile(ctx, username)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toProfile(profileDB)
+}
+
+func (s *UsersService) GetProfileByID(ctx context.Context, userID int) (*domain.Profile, error) {
+	profileDB, err := s.repo.GetProfileByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toProfile(profileDB)
+}
+
+func (s *UsersService) UpdateProfile(ctx context.Context, updateProfile domain.UpdateProfile) error {
+	if err := validateProfile(updateProfile); err != nil {
+		return err
+	}
+	links := updateProfile.SocialLinks
+	if links == nil {
+		links = map[string]string{}
+	}
+	raw, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateProfile(ctx, repository.UpdateProfile{
+		UserID:      updateProfile.UserID,
+		DisplayName: updateProfile.DisplayName,
+		Bio:         updateProfile.Bio,
+		AvatarURL:   updateProfile.AvatarURL,
+		Website:     updateProfile.Website,
+		SocialLinks: raw,
+	})
+}
+
+func validateProfile(p domain.UpdateProfile) error {
+	v := &ValidationError{}
+	if utf8.RuneCountInString(p.DisplayName) > maxDisplayNameLength {
+		v.add("display_name", fmt.Sprintf("display name must be at most %d characters", maxDisplayNameLength))
+	}
+	if utf8.RuneCountInString(p.Bio) > maxBioLength {
+		v.add("bio", fmt.Sprintf("bio must be at most %d characters", maxBioLength))
+	}
+	if p.AvatarURL != "" && !isWebURL(p.AvatarURL) {
+		v.add("avatar_url", ErrInvalidURL.Error())
+	}
+	if p.Website != "" && !isWebURL(p.Website) {
+		v.add("website", ErrInvalidURL.Error())
+	}
+	if len(p.SocialLinks) > maxSocialLinks {
+		v.add("social_links", fmt.Sprintf("at most %d social links are allowed", maxSocialLinks))
+	}
+	names := make([]string, 0, len(p.SocialLinks))
+	for name := range p.SocialLinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "social_links." + name
+		if name == "" || len(name) > maxSocialNameLength {
+			v.add(field, fmt.Sprintf("link names must be 1 to %d characters", maxSocialNameLength))
+			continue
+		}
+		if !isWebURL(p.SocialLinks[name]) {
+			v.add(field, ErrInvalidURL.Error())
+		}
+	}
+	return v.errOrNil()
+}
+
+func isWebURL(s string) bool {
+	if len(s) > maxURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func toProfile(p *repository.Profile) (*domain.Profile, error) {
+	links := make(map[string]string)
+	if len(p.SocialLinks) > 0 {
+		if err := json.Unmarshal(p.SocialLinks, &links); err != nil {
+			return nil, err
+		}
+	}
+	return &domain.Profile{
+		UserID:      p.UserID,
+		Username:    p.Username,
+		DisplayName: p.DisplayName,
+		Bio:         p.Bio,
+		AvatarURL:   p.AvatarURL,
+		Website:     p.Website,
+		SocialLinks: links,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+)
+
+var ErrInvalidRedirectKind = errors.New("redirect kind must be post or tag")
+
+// SlugMovedError is returned by slug lookups that matched a previous slug
+// of a post or tag. Slug is the one in use now.
+type SlugMovedError struct {
+	Slug string
+}
+
+func (e *SlugMovedError) Error() string {
+	return "slug has moved to " + e.Slug
+}
+
+type RedirectsService struct {
+	repo repository.SlugRedirectsRepo
+	log  logger.Logger
+}
+
+func NewRedirectsService(repo repository.SlugRedirectsRepo, log logger.Logger) *RedirectsService {
+	return &RedirectsService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+func (s *RedirectsService) GetRedirects(ctx context.Context, kind string) ([]*domain.SlugRedirect, error) {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return nil, err
+	}
+	if kind != "" && kind != domain.RedirectKindPost && kind != domain.RedirectKindTag {
+		return nil, ErrInvalidRedirectKind
+	}
+	dbRedirects, err := s.repo.GetSlugRedirects(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.SlugRedirect, 0, len(dbRedirects))
+	for _, r := range dbRedirects {
+		out = append(out, &domain.SlugRedirect{
+			ID:         r.ID,
+			Kind:       r.Kind,
+			Slug:       r.Slug,
+			TargetID:   r.TargetID,
+			TargetSlug: r.TargetSlug.String,
+			Stale:      !r.TargetSlug.Valid,
+			CreatedAt:  r.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (s *RedirectsService) DeleteRedirect(ctx context.Context, id int) error {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return err
+	}
+	err := s.repo.DeleteSlugRedirect(ctx, id)
+	if err == repository.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// redirectTarget finds the id a previous slug points to.
+func redirectTarget(ctx context.Context, repo repository.SlugRedirectsRepo, kind, slug string) (int, error) {
+	redirect, err := repo.GetSlugRedirect(ctx, kind, slug)
+	if err == repository.ErrNotFound || (err == nil && !redirect.TargetSlug.Valid) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return redirect.TargetID, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/mailer"
+	"github.com/scraletteykt/my-blog/pkg/oidc"
+	"github.com/scraletteykt/my-blog/pkg/render"
+	"github.com/scraletteykt/my-blog/pkg/sanitize"
+	"github.com/scraletteykt/my-blog/pkg/sign"
+	"github.com/scraletteykt/my-blog/pkg/token"
+)
+
+type Services struct {
+	Users         *UsersService
+	Posts         *PostsService
+	Tags          *TagsService
+	Tokens        *TokensService
+	Sessions      *SessionsService
+	Accounts      *AccountsService
+	MFA           *MFAService
+	APITokens     *APITokensService
+	LoginThrottle *LoginThrottleService
+	OIDC          *OIDCService
+	AccountData   *AccountDataService
+	Passwords     *PasswordsService
+	Redirects     *RedirectsService
+	Publisher     *Publisher
+}
+
+func NewServices(repo *repository.Repositories, cfg *config.Config, keys *sign.KeyRing, m mailer.Mailer, log logger.Logger) *Services {
+	tokenManager := token.NewManager(keys, cfg.Auth.AccessTokenTTL)
+	oidcProvider := oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.Auth.OIDC.Issuer,
+		ClientID:     cfg.Auth.OIDC.ClientID,
+		ClientSecret: cfg.Auth.OIDC.ClientSecret,
+		RedirectURL:  cfg.Auth.OIDC.RedirectURL,
+		Scopes:       cfg.Auth.OIDC.Scopes,
+	}, nil)
+	sanitizer := sanitize.New(sanitize.Config{
+		URLSchemes: cfg.Content.Sanitizer.URLSchemes,
+		Elements:   cfg.Content.Sanitizer.Elements,
+		Attributes: cfg.Content.Sanitizer.Attributes,
+	})
+	return &Services{
+		Users:         NewUsersService(*repo.Users, log),
+		Posts:         NewPostsService(*repo.Posts, *repo.Tags, *repo.SlugRedirects, *repo.PostRevisions, render.New(sanitizer), sanitizer, readingStats(cfg.Content), cfg.Content.SearchLanguage, log),
+		Tags:          NewTagsService(*repo.Tags, *repo.SlugRedirects, log),
+		Tokens:        NewTokensService(*repo.RefreshTokens, *repo.Sessions, *repo.Users, tokenManager, cfg.Auth.RefreshTokenTTL, log),
+		Sessions:      NewSessionsService(*repo.Sessions, *repo.Users, cfg.Auth.SessionTTL, log),
+		Accounts:      NewAccountsService(*repo.Users, *repo.UserTokens, *repo.Sessions, *repo.APITokens, m, cfg.Mail.BaseURL, cfg.Auth.VerifyEmailTTL, cfg.Auth.PasswordResetTTL, log),
+		MFA:           NewMFAService(*repo.Users, *repo.UserTokens, *repo.RecoveryCodes, cfg.Auth.TOTPIssuer, cfg.Auth.MFAChallengeTTL, log),
+		APITokens:     NewAPITokensService(*repo.APITokens, *repo.Users, cfg.Auth.APITokenTTL, cfg.Auth.APITokenMaxTTL, log),
+		LoginThrottle: NewLoginThrottleService(*repo.LoginFailures, *repo.Audit, cfg.Auth.Lockout, log),
+		OIDC:          NewOIDCService(repo.Users, repo.UserIdentities, oidcProvider, cfg.Auth.OIDC, log),
+		AccountData:   NewAccountDataService(*repo.Users, *repo.Posts, *repo.Sessions, *repo.APITokens, *repo.UserIdentities, *repo.Audit, log),
+		Passwords:     NewPasswordsService(*repo.Users, cfg.Auth.Password, log),
+		Redirects:     NewRedirectsService(*repo.SlugRedirects, log),
+		Publisher:     NewPublisher(*repo.Posts, cfg.Content.Publisher.Interval, cfg.Content.Publisher.BatchSize, log),
+	}
+}
+
+func readingStats(cfg config.ContentConfig) render.StatsConfig {
+	return render.StatsConfig{
+		WordsPerMinute:     cfg.ReadingTime.WordsPerMinute,
+		CJKCharsPerMinute:  cfg.ReadingTime.CJKCharsPerMinute,
+		CodeWordsPerMinute: cfg.ReadingTime.CodeWordsPerMinute,
+		ImageSeconds:       cfg.ReadingTime.ImageSeconds,
+		ExcerptLength:      cfg.ExcerptLength,
+	}
+}
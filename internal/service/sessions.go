@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/token"
+	"time"
+)
+
+const sessionTouchInterval = time.Minute
+
+var ErrInvalidSession = errors.New("session is expired or revoked")
+
+type SessionsService struct {
+	sessionsRepo repository.SessionsRepo
+	usersRepo    repository.UsersRepo
+	ttl          time.Duration
+	log          logger.Logger
+}
+
+func NewSessionsService(sessionsRepo repository.SessionsRepo, usersRepo repository.UsersRepo, ttl time.Duration, log logger.Logger) *SessionsService {
+	return &SessionsService{
+		sessionsRepo: sessionsRepo,
+		usersRepo:    usersRepo,
+		ttl:          ttl,
+		log:          log,
+	}
+}
+
+func (s *SessionsService) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *SessionsService) CreateSession(ctx context.Context, user domain.User, ip, userAgent string) (*domain.Session, string, error) {
+	sessionToken, err := token.NewOpaque()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := &domain.Session{
+		UserID:     user.ID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+		Current:    true,
+	}
+	session.ID, err = s.sessionsRepo.CreateSession(ctx, repository.CreateSession{
+		UserID:    session.UserID,
+		TokenHash: token.Hash(sessionToken),
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return session, sessionToken, nil
+}
+
+func (s *SessionsService) Authenticate(ctx context.Context, sessionToken string) (*domain.User, *domain.Session, error) {
+	dbSession, err := s.sessionsRepo.GetSessionByHash(ctx, token.Hash(sessionToken))
+	if err == repository.ErrNotFound {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !isSessionActive(dbSession, now) {
+		return nil, nil, ErrInvalidSession
+	}
+	userDB, err := s.usersRepo.GetUserByID(ctx, dbSession.UserID)
+	if err == repository.ErrNotFound {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.Sub(dbSession.LastSeenAt) > sessionTouchInterval {
+		if err := s.sessionsRepo.TouchSession(ctx, dbSession.ID, now); err != nil {
+			s.log.Warnf("warn: touch session %d: %s", dbSession.ID, err.Error())
+		}
+		dbSession.LastSeenAt = now
+	}
+	session := toDomainSession(dbSession)
+	session.Current = true
+	return userDB, session, nil
+}
+
+func (s *SessionsService) IsActive(ctx context.Context, sessionID int) (bool, error) {
+	dbSession, err := s.sessionsRepo.GetSessionByID(ctx, sessionID)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return isSessionActive(dbSession, time.Now()), nil
+}
+
+func (s *SessionsService) GetSessions(ctx context.Context, userID, currentSessionID int) ([]*domain.Session, error) {
+	dbSessions, err := s.sessionsRepo.GetActiveSessionsByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		session := toDomainSession(dbSession)
+		session.Current = session.ID == currentSessionID
+		out = append(out, session)
+	}
+	return out, nil
+}
+
+func (s *SessionsService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	ok, err := s.sessionsRepo.RevokeSession(ctx, sessionID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SessionsService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID int) error {
+	return s.sessionsRepo.RevokeUserSessions(ctx, userID, currentSessionID, time.Now())
+}
+
+func isSessionActive(s *repository.Session, now time.Time) bool {
+	return !s.RevokedAt.Valid && now.Before(s.ExpiresAt)
+}
+
+func toDomainSession(s *repository.Session) *domain.Session {
+	return &domain.Session{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		IP:         s.IP.String,
+		UserAgent:  s.UserAgent.String,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"github.com/scraletteykt/my-blog/pkg/slug"
+)
+
+var (
+	ErrInvalidSlug = errors.New("slug may only contain lowercase letters, digits and single hyphens")
+	ErrSlugTaken   = errors.New("slug is already in use")
+)
+
+// slugTakenError reports a slug collision against the slug field.
+func slugTakenError() error {
+	return &ValidationError{Fields: []FieldError{{Field: "slug", Message: ErrSlugTaken.Error()}}}
+}
+
+// resolveSlug returns the requested slug if it is free, or derives one from
+// source and suffixes it until it no longer collides. taken lists the slugs
+// in use that start with the given base.
+func resolveSlug(requested, source, fallback string, taken func(base string) ([]string, error)) (string, error) {
+	base := requested
+	if base == "" {
+		base = slug.Make(source)
+	}
+	if base == "" {
+		base = fallback
+	}
+	used, err := taken(base)
+	if err != nil {
+		return "", err
+	}
+	inUse := make(map[string]bool, len(used))
+	for _, s := range used {
+		inUse[s] = true
+	}
+	if !inUse[base] {
+		return base, nil
+	}
+	if requested != "" {
+		return "", slugTakenError()
+	}
+	for n := 2; ; n++ {
+		if s := slug.WithSuffix(base, n); !inUse[s] {
+			return s, nil
+		}
+	}
+}
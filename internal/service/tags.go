@@ -2,20 +2,33 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/scraletteykt/my-blog/internal/domain"
 	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/slug"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+const maxTagNameLength = 255
+
+var ErrTagNameTaken = errors.New("a tag with this name already exists")
+
 type TagsService struct {
-	tagsRepo repository.TagsRepo
-	log      logger.Logger
+	tagsRepo      repository.TagsRepo
+	redirectsRepo repository.SlugRedirectsRepo
+	log           logger.Logger
 }
 
-func NewTagsService(repo repository.TagsRepo, log logger.Logger) *TagsService {
+func NewTagsService(repo repository.TagsRepo, redirectsRepo repository.SlugRedirectsRepo, log logger.Logger) *TagsService {
 	return &TagsService{
-		tagsRepo: repo,
-		log:      log,
+		tagsRepo:      repo,
+		redirectsRepo: redirectsRepo,
+		log:           log,
 	}
 }
 
@@ -58,33 +71,124 @@ func (t *TagsService) GetTagByID(ctx context.Context, tagID int) (*domain.Tag, e
 	}, nil
 }
 
+// GetTagBySlug looks a tag up by its slug. A previous slug of a tag yields
+// a SlugMovedError carrying the current one.
+func (t *TagsService) GetTagBySlug(ctx context.Context, slug string) (*domain.Tag, error) {
+	dbTag, err := t.tagsRepo.GetTagBySlug(ctx, slug)
+	if err == repository.ErrNotFound {
+		return nil, t.movedTag(ctx, slug)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Tag{
+		ID:   dbTag.ID,
+		Name: dbTag.Name,
+		Slug: dbTag.Slug,
+	}, nil
+}
+
 func (t *TagsService) CreateTag(ctx context.Context, createTag domain.CreateTag) error {
-	_, err := t.tagsRepo.CreateTag(ctx, repository.CreateTag{
-		Name: createTag.Name,
-		Slug: createTag.Slug,
-	})
+	if err := authorize(ctx, auth.PermissionTagsWrite); err != nil {
+		return err
+	}
+	if err := validateTag(createTag.Name, createTag.Slug); err != nil {
+		return err
+	}
+	tagSlug, err := t.resolveSlug(ctx, createTag.Slug, createTag.Name, 0)
 	if err != nil {
 		return err
 	}
-	return nil
+	_, err = t.tagsRepo.CreateTag(ctx, repository.CreateTag{
+		Name:      createTag.Name,
+		Slug:      tagSlug,
+		CreatedAt: time.Now(),
+	})
+	return tagWriteError(err)
 }
 
 func (t *TagsService) UpdateTag(ctx context.Context, updateTag domain.UpdateTag) error {
-	err := t.tagsRepo.UpdateTag(ctx, repository.UpdateTag{
-		ID:   updateTag.ID,
-		Name: updateTag.Name,
-		Slug: updateTag.Slug,
-	})
+	if err := authorize(ctx, auth.PermissionTagsWrite); err != nil {
+		return err
+	}
+	if err := validateTag(updateTag.Name, updateTag.Slug); err != nil {
+		return err
+	}
+	current, err := t.tagsRepo.GetTagByID(ctx, updateTag.ID)
+	if err == repository.ErrNotFound {
+		return ErrNotFound
+	}
 	if err != nil {
 		return err
 	}
-	return nil
+	tagSlug, err := t.resolveSlug(ctx, updateTag.Slug, updateTag.Name, updateTag.ID)
+	if err != nil {
+		return err
+	}
+	err = t.tagsRepo.UpdateTag(ctx, repository.UpdateTag{
+		ID:           updateTag.ID,
+		Name:         updateTag.Name,
+		Slug:         tagSlug,
+		PreviousSlug: current.Slug,
+		UpdatedAt:    time.Now(),
+	})
+	return tagWriteError(err)
 }
 
 func (t *TagsService) DeleteTag(ctx context.Context, deleteTag domain.DeleteTag) error {
+	if err := authorize(ctx, auth.PermissionTagsWrite); err != nil {
+		return err
+	}
 	err := t.tagsRepo.DeleteTag(ctx, repository.DeleteTag{ID: deleteTag.ID})
 	if err != nil {
 		return err
 	}
-	return nil
+	return t.redirectsRepo.DeleteSlugRedirectsByTarget(ctx, domain.RedirectKindTag, deleteTag.ID)
+}
+
+func (t *TagsService) movedTag(ctx context.Context, slug string) error {
+	tagID, err := redirectTarget(ctx, t.redirectsRepo, domain.RedirectKindTag, slug)
+	if err != nil {
+		return err
+	}
+	tag, err := t.GetTagByID(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	return &SlugMovedError{Slug: tag.Slug}
+}
+
+func (t *TagsService) resolveSlug(ctx context.Context, requested, name string, tagID int) (string, error) {
+	return resolveSlug(requested, name, "tag", func(base string) ([]string, error) {
+		return t.tagsRepo.GetSlugsLike(ctx, base, tagID)
+	})
+}
+
+// tagWriteError reports a name or slug taken by a concurrent write against
+// its field.
+func tagWriteError(err error) error {
+	switch err {
+	case repository.ErrSlugTaken:
+		return slugTakenError()
+	case repository.ErrNameTaken:
+		return &ValidationError{Fields: []FieldError{{Field: "name", Message: ErrTagNameTaken.Error()}}}
+	}
+	return err
+}
+
+func validateTag(name, tagSlug string) error {
+	v := &ValidationError{}
+	switch {
+	case strings.TrimSpace(name) == "":
+		v.add("name", "name is required")
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		v.add("name", fmt.Sprintf("name must be at most %d characters", maxTagNameLength))
+	}
+	switch {
+	case utf8.RuneCountInString(tagSlug) > maxSlugLength:
+		v.add("slug", fmt.Sprintf("slug must be at most %d characters", maxSlugLength))
+	case tagSlug != "" && !slug.IsValid(tagSlug):
+		v.add("slug", ErrInvalidSlug.Error())
+	}
+	return v.errOrNil()
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/scraletteykt/my-blog/internal/domain"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"github.com/scraletteykt/my-blog/pkg/token"
+	"time"
+)
+
+const tokenTypeBearer = "Bearer"
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrTokenReused  = errors.New("refresh token reuse detected")
+)
+
+type TokensService struct {
+	refreshTokensRepo repository.RefreshTokensRepo
+	sessionsRepo      repository.SessionsRepo
+	usersRepo         repository.UsersRepo
+	manager           *token.Manager
+	refreshTTL        time.Duration
+	log               logger.Logger
+}
+
+func NewTokensService(refreshTokensRepo repository.RefreshTokensRepo, sessionsRepo repository.SessionsRepo, usersRepo repository.UsersRepo, manager *token.Manager, refreshTTL time.Duration, log logger.Logger) *TokensService {
+	return &TokensService{
+		refreshTokensRepo: refreshTokensRepo,
+		sessionsRepo:      sessionsRepo,
+		usersRepo:         usersRepo,
+		manager:           manager,
+		refreshTTL:        refreshTTL,
+		log:               log,
+	}
+}
+
+func (t *TokensService) IssueTokens(ctx context.Context, user domain.User, sessionID int) (*domain.TokenPair, error) {
+	family, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(ctx, user, sessionID, family)
+}
+
+func (t *TokensService) RefreshTokens(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	dbToken, err := t.refreshTokensRepo.GetRefreshTokenByHash(ctx, token.Hash(refreshToken))
+	if err == repository.ErrNotFound {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if dbToken.RevokedAt.Valid || now.After(dbToken.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if dbToken.UsedAt.Valid {
+		return nil, t.revokeReused(ctx, dbToken)
+	}
+	dbSession, err := t.sessionsRepo.GetSessionByID(ctx, dbToken.SessionID)
+	if err == repository.ErrNotFound {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isSessionActive(dbSession, now) {
+		return nil, ErrInvalidSession
+	}
+	ok, err := t.refreshTokensRepo.UseRefreshToken(ctx, dbToken.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, t.revokeReused(ctx, dbToken)
+	}
+	userDB, err := t.usersRepo.GetUserByID(ctx, dbToken.UserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(ctx, *userDB, dbToken.SessionID, dbToken.Family)
+}
+
+func (t *TokensService) ParseAccessToken(accessToken string) (auth.User, error) {
+	claims, err := t.manager.Parse(accessToken)
+	if err != nil {
+		return auth.User{}, ErrInvalidToken
+	}
+	return auth.User{
+		ID:        claims.UserID,
+		Username:  claims.Username,
+		Role:      claims.Role,
+		SessionID: claims.SessionID,
+	}, nil
+}
+
+func (t *TokensService) issue(ctx context.Context, user domain.User, sessionID int, family string) (*domain.TokenPair, error) {
+	accessToken, _, err := t.manager.NewAccessToken(user.ID, user.Username, user.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	refreshExpiresAt := now.Add(t.refreshTTL)
+	_, err = t.refreshTokensRepo.CreateRefreshToken(ctx, repository.CreateRefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		Family:    family,
+		TokenHash: token.Hash(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TokenPair{
+		AccessToken:           accessToken,
+		TokenType:             tokenTypeBearer,
+		ExpiresIn:             int(t.manager.TTL().Seconds()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (t *TokensService) revokeReused(ctx context.Context, dbToken *repository.RefreshToken) error {
+	t.log.Warnf("warn: refresh token reuse detected for user %d, revoking token family", dbToken.UserID)
+	if err := t.refreshTokensRepo.RevokeRefreshTokenFamily(ctx, dbToken.Family, time.Now()); err != nil {
+		return err
+	}
+	return ErrTokenReused
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/token"
+	"time"
+)
+
+const (
+	userTokenPurposeVerifyEmail   = "verify_email"
+	userTokenPurposeResetPassword = "reset_password"
+	userTokenPurposeMFAChallenge  = "mfa_challenge"
+)
+
+func issueUserToken(ctx context.Context, repo repository.UserTokensRepo, userID int, purpose string, ttl time.Duration) (string, error) {
+	t, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_, err = repo.CreateUserToken(ctx, repository.CreateUserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: token.Hash(t),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return t, nil
+}
+
+func useUserToken(ctx context.Context, repo repository.UserTokensRepo, purpose, t string) (int, error) {
+	dbToken, err := repo.GetUserToken(ctx, purpose, token.Hash(t))
+	if err == repository.ErrNotFound {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dbToken.UsedAt.Valid || time.Now().After(dbToken.ExpiresAt) {
+		return 0, ErrInvalidToken
+	}
+	ok, err := repo.UseUserToken(ctx, dbToken.ID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	return dbToken.UserID, nil
+}
@@ -5,12 +5,19 @@ import (
 	"errors"
 	"github.com/scraletteykt/my-blog/internal/domain"
 	"github.com/scraletteykt/my-blog/internal/repository"
+	"github.com/scraletteykt/my-blog/pkg/auth"
 	"github.com/scraletteykt/my-blog/pkg/logger"
+	"net/mail"
+	"strings"
 )
 
 var (
 	ErrForbidden         = errors.New("wrong username or password")
 	ErrUserAlreadyExists = errors.New("user with given username already exists")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrInvalidUsername   = errors.New("username must not be empty")
+	ErrEmailAlreadyUsed  = errors.New("user with given email already exists")
 )
 
 type UsersService struct {
@@ -35,20 +42,60 @@ func (s *UsersService) GetUser(ctx context.Context, username string) (*domain.Us
 	}
 
 	user := &domain.User{
-		ID:           userDB.ID,
-		Username:     userDB.Username,
-		PasswordHash: userDB.PasswordHash,
+		ID:            userDB.ID,
+		Username:      userDB.Username,
+		PasswordHash:  userDB.PasswordHash,
+		Role:          userDB.Role,
+		Email:         userDB.Email,
+		EmailVerified: userDB.EmailVerified,
+		TOTPSecret:    userDB.TOTPSecret,
+		TOTPEnabled:   userDB.TOTPEnabled,
 	}
 
 	return user, nil
 }
 
+// ValidateNewUser checks the fields of a new user that don't need the
+// database, so that sign-up can reject them before hashing the password.
+func (s *UsersService) ValidateNewUser(user domain.User) error {
+	if strings.TrimSpace(user.Username) == "" {
+		return ErrInvalidUsername
+	}
+	if user.Role != "" && !auth.IsValidRole(user.Role) {
+		return ErrInvalidRole
+	}
+	if user.Email != "" {
+		addr, err := mail.ParseAddress(user.Email)
+		if err != nil || addr.Address != user.Email {
+			return ErrInvalidEmail
+		}
+	}
+	return nil
+}
+
 func (s *UsersService) CreateUser(ctx context.Context, user domain.User) (*domain.User, error) {
+	if err := s.ValidateNewUser(user); err != nil {
+		return nil, err
+	}
+	if user.Role == "" {
+		user.Role = auth.RoleAuthor
+	}
+	if user.Email != "" {
+		_, err := s.repo.GetUserByEmail(ctx, user.Email)
+		if err == nil {
+			return nil, ErrEmailAlreadyUsed
+		}
+		if err != repository.ErrNotFound {
+			return nil, err
+		}
+	}
 	_, err := s.repo.GetUser(ctx, user.Username)
 	if err == repository.ErrNotFound {
 		id, err := s.repo.CreateUser(ctx, domain.User{
 			Username:     user.Username,
 			PasswordHash: user.PasswordHash,
+			Role:         user.Role,
+			Email:        user.Email,
 		})
 		if err != nil {
 			return nil, err
@@ -59,9 +106,14 @@ func (s *UsersService) CreateUser(ctx context.Context, user domain.User) (*domai
 			return nil, err
 		}
 		u := &domain.User{
-			ID:           userDB.ID,
-			Username:     userDB.Username,
-			PasswordHash: userDB.PasswordHash,
+			ID:            userDB.ID,
+			Username:      userDB.Username,
+			PasswordHash:  userDB.PasswordHash,
+			Role:          userDB.Role,
+			Email:         userDB.Email,
+			EmailVerified: userDB.EmailVerified,
+			TOTPSecret:    userDB.TOTPSecret,
+			TOTPEnabled:   userDB.TOTPEnabled,
 		}
 
 		return u, nil
@@ -71,3 +123,27 @@ func (s *UsersService) CreateUser(ctx context.Context, user domain.User) (*domai
 		return nil, ErrUserAlreadyExists
 	}
 }
+
+func (s *UsersService) GetUsers(ctx context.Context) ([]*domain.User, error) {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUsers(ctx)
+}
+
+func (s *UsersService) UpdateUserRole(ctx context.Context, updateRole domain.UpdateUserRole) error {
+	if err := authorize(ctx, auth.PermissionUsersManage); err != nil {
+		return err
+	}
+	if !auth.IsValidRole(updateRole.Role) {
+		return ErrInvalidRole
+	}
+	_, err := s.repo.GetUserByID(ctx, updateRole.ID)
+	if err == repository.ErrNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateUserRole(ctx, updateRole.ID, updateRole.Role)
+}
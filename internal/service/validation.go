@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError collects every invalid field of a request so clients can
+// fix them all at once.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		names = append(names, f.Field)
+	}
+	return fmt.Sprintf("invalid fields: %s", strings.Join(names, ", "))
+}
+
+func (e *ValidationError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
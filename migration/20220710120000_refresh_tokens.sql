@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE refresh_tokens
+(
+    id         SERIAL NOT NULL UNIQUE,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    family     VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    revoked_at TIMESTAMP,
+    CONSTRAINT pk_refresh_tokens PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens (family);
+-- +goose Down
+DROP TABLE refresh_tokens;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE sessions
+(
+    id           SERIAL NOT NULL UNIQUE,
+    user_id      INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    ip           VARCHAR(64),
+    user_agent   TEXT,
+    created_at   TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP,
+    CONSTRAINT pk_sessions PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+DELETE FROM refresh_tokens;
+ALTER TABLE refresh_tokens
+    ADD COLUMN session_id INTEGER REFERENCES sessions (id) ON DELETE CASCADE NOT NULL;
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN session_id;
+DROP TABLE sessions;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN email             VARCHAR(255) UNIQUE,
+    ADD COLUMN email_verified_at TIMESTAMP;
+
+CREATE TABLE user_tokens
+(
+    id         SERIAL NOT NULL UNIQUE,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    purpose    VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    CONSTRAINT pk_user_tokens PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id, purpose);
+-- +goose Down
+DROP TABLE user_tokens;
+ALTER TABLE users
+    DROP COLUMN email,
+    DROP COLUMN email_verified_at;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN totp_secret    VARCHAR(64),
+    ADD COLUMN totp_enabled   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE recovery_codes
+(
+    id         SERIAL NOT NULL UNIQUE,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    CONSTRAINT pk_recovery_codes PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+-- +goose Down
+DROP TABLE recovery_codes;
+ALTER TABLE users
+    DROP COLUMN totp_secret,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_last_step;
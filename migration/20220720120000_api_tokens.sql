@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE api_tokens
+(
+    id           SERIAL NOT NULL UNIQUE,
+    user_id      INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(32) NOT NULL,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP,
+    CONSTRAINT pk_api_tokens PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);
+-- +goose Down
+DROP TABLE api_tokens;
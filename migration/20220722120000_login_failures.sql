@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE login_failures
+(
+    key            VARCHAR(320) NOT NULL UNIQUE,
+    failures       INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until   TIMESTAMP,
+    CONSTRAINT pk_login_failures PRIMARY KEY (key)
+);
+
+CREATE TABLE audit_log
+(
+    id         SERIAL NOT NULL UNIQUE,
+    action     VARCHAR(64) NOT NULL,
+    user_id    INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    ip         VARCHAR(64),
+    details    TEXT,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_audit_log PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_log_user_id ON audit_log (user_id);
+-- +goose Down
+DROP TABLE audit_log;
+DROP TABLE login_failures;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE user_identities
+(
+    id         SERIAL NOT NULL UNIQUE,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255),
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_identities PRIMARY KEY (id),
+    CONSTRAINT uq_user_identities_subject UNIQUE (issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+-- +goose Down
+DROP TABLE user_identities;
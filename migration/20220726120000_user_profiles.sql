@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN bio          TEXT NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN website      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN social_links JSONB NOT NULL DEFAULT '{}';
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN display_name,
+    DROP COLUMN bio,
+    DROP COLUMN avatar_url,
+    DROP COLUMN website,
+    DROP COLUMN social_links;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN deletion_requested_at TIMESTAMP,
+    ADD COLUMN deleted_at            TIMESTAMP;
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN deletion_requested_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN format         VARCHAR(16) NOT NULL DEFAULT 'markdown',
+    ADD COLUMN content_html   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN render_version INTEGER NOT NULL DEFAULT 0;
+-- +goose Down
+ALTER TABLE posts
+    DROP COLUMN format,
+    DROP COLUMN content_html,
+    DROP COLUMN render_version;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN word_count          INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN excerpt             TEXT NOT NULL DEFAULT '',
+    ADD COLUMN reading_time_manual BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN excerpt_manual      BOOLEAN NOT NULL DEFAULT FALSE;
+-- Reading times stored so far were sent by clients.
+UPDATE posts SET reading_time_manual = TRUE WHERE reading_time > 0;
+UPDATE posts SET reading_time = 0 WHERE reading_time IS NULL;
+ALTER TABLE posts
+    ALTER COLUMN reading_time SET DEFAULT 0,
+    ALTER COLUMN reading_time SET NOT NULL;
+-- +goose Down
+ALTER TABLE posts
+    ALTER COLUMN reading_time DROP NOT NULL,
+    ALTER COLUMN reading_time DROP DEFAULT,
+    DROP COLUMN word_count,
+    DROP COLUMN excerpt,
+    DROP COLUMN reading_time_manual,
+    DROP COLUMN excerpt_manual;
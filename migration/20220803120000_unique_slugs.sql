@@ -0,0 +1,28 @@
+-- +goose Up
+-- Posts used to be created with their content as slug. Derive slugs from
+-- titles for anything that is not slug shaped and suffix duplicates with
+-- the row id, keeping the oldest row on the plain slug.
+UPDATE posts
+SET slug = trim(BOTH '-' FROM regexp_replace(lower(title), '[^a-z0-9]+', '-', 'g'))
+WHERE slug IS NULL OR slug !~ '^[a-z0-9]+(-[a-z0-9]+)*$';
+UPDATE posts SET slug = 'post' WHERE slug = '';
+UPDATE posts p
+SET slug = p.slug || '-' || p.id
+FROM (SELECT id, row_number() OVER (PARTITION BY slug ORDER BY id) AS n FROM posts) d
+WHERE d.id = p.id AND d.n > 1;
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX posts_slug_key ON posts (slug);
+
+UPDATE tags
+SET slug = trim(BOTH '-' FROM regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g'))
+WHERE slug !~ '^[a-z0-9]+(-[a-z0-9]+)*$';
+UPDATE tags SET slug = 'tag' WHERE slug = '';
+UPDATE tags t
+SET slug = t.slug || '-' || t.id
+FROM (SELECT id, row_number() OVER (PARTITION BY slug ORDER BY id) AS n FROM tags) d
+WHERE d.id = t.id AND d.n > 1;
+CREATE UNIQUE INDEX tags_slug_key ON tags (slug);
+-- +goose Down
+DROP INDEX tags_slug_key;
+DROP INDEX posts_slug_key;
+ALTER TABLE posts ALTER COLUMN slug DROP NOT NULL;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE slug_redirects
+(
+    id         SERIAL NOT NULL UNIQUE,
+    kind       VARCHAR(16) NOT NULL,
+    slug       VARCHAR(255) NOT NULL,
+    target_id  INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_slug_redirects PRIMARY KEY (id),
+    CONSTRAINT slug_redirects_kind_slug_key UNIQUE (kind, slug)
+);
+CREATE INDEX slug_redirects_target_idx ON slug_redirects (kind, target_id);
+-- +goose Down
+DROP TABLE slug_redirects;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN updated_by INTEGER REFERENCES users (id) ON DELETE SET NULL;
+UPDATE posts SET updated_by = user_id;
+
+CREATE TABLE post_revisions
+(
+    id         SERIAL NOT NULL UNIQUE,
+    post_id    INTEGER REFERENCES posts (id) ON DELETE CASCADE NOT NULL,
+    editor_id  INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    status     INTEGER NOT NULL,
+    title      VARCHAR(255) NOT NULL,
+    subtitle   TEXT NOT NULL,
+    image_url  TEXT NOT NULL,
+    content    TEXT NOT NULL,
+    format     VARCHAR(16) NOT NULL,
+    slug       VARCHAR(255) NOT NULL,
+    tags       JSONB NOT NULL DEFAULT '[]',
+    edited_at  TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_post_revisions PRIMARY KEY (id)
+);
+CREATE INDEX post_revisions_post_id_idx ON post_revisions (post_id, id);
+-- +goose Down
+DROP TABLE post_revisions;
+ALTER TABLE posts DROP COLUMN updated_by;
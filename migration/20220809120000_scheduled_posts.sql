@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP;
+CREATE INDEX posts_publish_at_idx ON posts (publish_at) WHERE status = 15;
+-- +goose Down
+UPDATE posts SET status = 10 WHERE status = 15;
+DROP INDEX posts_publish_at_idx;
+ALTER TABLE posts DROP COLUMN publish_at;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN search_config REGCONFIG NOT NULL DEFAULT 'english';
+-- Content is indexed from the rendered HTML with the markup stripped so
+-- that markdown syntax does not end up in the index or in snippets.
+ALTER TABLE posts ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector(search_config, COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector(search_config, COALESCE(subtitle, '')), 'B') ||
+    setweight(to_tsvector(search_config, regexp_replace(content_html, '<[^>]*>', ' ', 'g')), 'C')
+) STORED;
+CREATE INDEX posts_search_idx ON posts USING GIN (search_vector);
+-- +goose Down
+DROP INDEX posts_search_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts DROP COLUMN search_config;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN views BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX posts_published_at_idx ON posts (published_at, id);
+CREATE INDEX posts_views_idx ON posts (views, id);
+-- +goose Down
+DROP INDEX posts_views_idx;
+DROP INDEX posts_published_at_idx;
+ALTER TABLE posts DROP COLUMN views;
@@ -8,8 +8,10 @@ var (
 	contextKeyUser = contextKey("ctx_user")
 
 	failedContextUser = User{
-		ID:       -1,
-		Username: "",
+		ID:        -1,
+		Username:  "",
+		Role:      "",
+		SessionID: -1,
 	}
 )
 
@@ -0,0 +1,72 @@
+package auth
+
+type Permission string
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleReader = "reader"
+)
+
+const (
+	PermissionPostsWrite    Permission = "posts:write"
+	PermissionPostsModerate Permission = "posts:moderate"
+	PermissionTagsWrite     Permission = "tags:write"
+	PermissionUsersManage   Permission = "users:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:  {PermissionPostsWrite, PermissionPostsModerate, PermissionTagsWrite, PermissionUsersManage},
+	RoleEditor: {PermissionPostsWrite, PermissionPostsModerate, PermissionTagsWrite},
+	RoleAuthor: {PermissionPostsWrite},
+	RoleReader: {},
+}
+
+func IsValidPermission(p Permission) bool {
+	for _, rp := range rolePermissions[RoleAdmin] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (u User) IsAuthenticated() bool {
+	return u.ID > 0
+}
+
+// IsScoped reports whether the request was authenticated with a credential
+// limited to a set of scopes, such as a personal access token.
+func (u User) IsScoped() bool {
+	return u.Scopes != nil
+}
+
+func (u User) HasScope(p Permission) bool {
+	if !u.IsScoped() {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (u User) Can(p Permission) bool {
+	if !u.IsAuthenticated() || !u.HasScope(p) {
+		return false
+	}
+	for _, rp := range rolePermissions[u.Role] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
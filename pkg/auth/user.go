@@ -1,6 +1,20 @@
 package auth
 
+// Source tells how the request was authenticated.
+type Source string
+
+const (
+	SourceNone     Source = ""
+	SourceCookie   Source = "cookie"
+	SourceBearer   Source = "bearer"
+	SourceAPIToken Source = "api_token"
+)
+
 type User struct {
-	ID       int
-	Username string
+	ID        int
+	Username  string
+	Role      string
+	SessionID int
+	Scopes    []Permission
+	Source    Source
 }
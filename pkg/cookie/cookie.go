@@ -8,28 +8,67 @@ import (
 
 const IDCookieName = "idCookie"
 const IDCookieSep = ":"
-const IDCookieMaxAge = 300_000_000
+const CSRFCookieName = "csrfToken"
 
 type IDCookie struct {
-	Username string
-	Sign     string
-	Cookie   *http.Cookie
+	SessionToken string
+	Sign         string
+	Cookie       *http.Cookie
 }
 
-func NewIDCookie(username string, sign string) *IDCookie {
-	value := username + IDCookieSep + sign
+func NewIDCookie(sessionToken string, sign string, maxAge int, secure bool) *IDCookie {
+	value := sessionToken + IDCookieSep + sign
 
 	cookie := &http.Cookie{
-		Name:   IDCookieName,
-		Value:  value,
-		MaxAge: IDCookieMaxAge,
-		Path:   "/",
+		Name:     IDCookieName,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
 	}
 
 	return &IDCookie{
-		Username: username,
-		Sign:     sign,
-		Cookie:   cookie,
+		SessionToken: sessionToken,
+		Sign:         sign,
+		Cookie:       cookie,
+	}
+}
+
+func NewExpiredIDCookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     IDCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// NewCSRFCookie is readable from scripts on purpose: browser clients copy
+// its value into the CSRF header of mutating requests.
+func NewCSRFCookie(token string, maxAge int, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		MaxAge:   maxAge,
+		Path:     "/",
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+func NewExpiredCSRFCookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
 	}
 }
 
@@ -46,12 +85,39 @@ func ParseFromCookie(cookie *http.Cookie) (*IDCookie, error) {
 		return nil, errors.New("invalid cookie value")
 	}
 
-	username := parsed[0]
+	sessionToken := parsed[0]
 	sign := parsed[1]
 
 	return &IDCookie{
-		Username: username,
-		Sign:     sign,
-		Cookie:   cookie,
+		SessionToken: sessionToken,
+		Sign:         sign,
+		Cookie:       cookie,
 	}, nil
 }
+
+const OIDCStateCookieName = "oidcState"
+const oidcStateCookiePath = "/api/auth/oidc"
+
+func NewOIDCStateCookie(value string, maxAge int, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     oidcStateCookiePath,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func NewExpiredOIDCStateCookie(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     oidcStateCookiePath,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,251 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change.
+const Context = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	// a and b are the line indexes in the old and new text.
+	a, b int
+}
+
+// Unified returns a unified diff of two texts, compared line by line, or an
+// empty string when they are equal.
+func Unified(fromName, toName, from, to string) string {
+	a, b := splitLines(from), splitLines(to)
+	ops := myers(a, b)
+
+	var out strings.Builder
+	for _, h := range hunks(ops) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		aStart, bStart := ops[h[0]].a, ops[h[0]].b
+		aLen, bLen := 0, 0
+		for _, o := range ops[h[0]:h[1]] {
+			if o.kind != opInsert {
+				aLen++
+			}
+			if o.kind != opDelete {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, o := range ops[h[0]:h[1]] {
+			line := ""
+			if o.kind == opInsert {
+				line = b[o.b]
+			} else {
+				line = a[o.a]
+			}
+			out.WriteByte(byte(o.kind))
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// hunks groups changes that are at most 2*Context unchanged lines apart and
+// returns the [start, end) op ranges of each group including its context.
+func hunks(ops []op) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := i - Context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*Context {
+				break
+			}
+			end = run
+		}
+		i = end
+		end += Context
+		if end > len(ops) {
+			end = len(ops)
+		}
+		out = append(out, [2]int{start, end})
+	}
+	return out
+}
+
+// maxCost bounds the line comparisons spent on one diff. Once it runs out
+// whatever is left to compare is shown as replaced whole, so two large and
+// unrelated revisions can't tie the server up.
+const maxCost = 1 << 24
+
+type differ struct {
+	a, b []string
+	ops  []op
+	cost int
+}
+
+// myers computes a shortest edit script between a and b with the linear
+// space variant of the algorithm: the middle snake of the edit graph splits
+// the texts in two halves that are compared on their own.
+func myers(a, b []string) []op {
+	d := &differ{a: a, b: b, ops: make([]op, 0, len(a)+len(b))}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, op{kind: opEqual, a: aLo, b: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+	x, y, ok := d.middleSnake(aLo, aHi, bLo, bHi)
+	if ok && (x > aLo || y > bLo) && (x < aHi || y < bHi) {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	} else {
+		for i := aLo; i < aHi; i++ {
+			d.ops = append(d.ops, op{kind: opDelete, a: i, b: bLo})
+		}
+		for j := bLo; j < bHi; j++ {
+			d.ops = append(d.ops, op{kind: opInsert, a: aHi, b: j})
+		}
+	}
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, op{kind: opEqual, a: aHi + i, b: bHi + i})
+	}
+}
+
+// middleSnake searches from both ends of a[aLo:aHi] and b[bLo:bHi] at once
+// for the point where the forward and backward paths meet. It reports false
+// when either side is empty or the cost budget runs out.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// vf and vb hold the furthest x reached on each diagonal going forward
+	// from the start and backward from the end.
+	vf, vb := make([]int, 2*maxD+2), make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the paths can only meet on a forward step.
+	front := delta%2 != 0
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for e := 0; e < maxD; e++ {
+		for k := -e + fStart; k <= e-fEnd; k += 2 {
+			var x int
+			if k == -e || (k != e && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+				d.cost++
+			}
+			vf[offset+k] = x
+			if d.cost++; d.cost > maxCost {
+				return 0, 0, false
+			}
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if kb := offset + delta - k; kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+		for k := -e + bStart; k <= e-bEnd; k += 2 {
+			var x int
+			if k == -e || (k != e && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+				d.cost++
+			}
+			vb[offset+k] = x
+			if d.cost++; d.cost > maxCost {
+				return 0, 0, false
+			}
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				if kf := offset + delta - k; kf >= 0 && kf < len(vf) && vf[kf] != -1 {
+					fx := vf[kf]
+					fy := fx - (kf - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "single line changed",
+			from: "a\n",
+			to:   "b\n",
+			want: "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name: "crlf line endings match lf",
+			from: "a\r\nb\r\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "missing trailing newline",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "crlf change",
+			from: "a\r\nb\r\nc\r\n",
+			to:   "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "context is trimmed",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			to:   "1\n2\n3\n4\n5x\n6\n7\n8\n9\n",
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+5x\n 6\n 7\n 8\n",
+		},
+		{
+			name: "insertion at the start",
+			from: "b\nc\n",
+			to:   "a\nb\nc\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n+a\n b\n c\n",
+		},
+		{
+			name: "close changes share a hunk",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			to:   "1x\n2\n3\n4\n5\n6\n7\n8x\n",
+			want: "--- old\n+++ new\n@@ -1,8 +1,8 @@\n-1\n+1x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+8x\n",
+		},
+		{
+			name: "distant changes get their own hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			to:   "1x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12x\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+1x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+12x\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.from, tt.to); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// lcs is the textbook quadratic longest common subsequence length, which a
+// shortest edit script must match.
+func lcs(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestMyersIsShortest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		out := make([]string, rnd.Intn(30))
+		for i := range out {
+			out[i] = strconv.Itoa(rnd.Intn(4))
+		}
+		return out
+	}
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		ops := myers(a, b)
+
+		var gotA, gotB []string
+		equal := 0
+		for _, o := range ops {
+			switch o.kind {
+			case opEqual:
+				if a[o.a] != b[o.b] {
+					t.Fatalf("%q -> %q: equal op on %q and %q", a, b, a[o.a], b[o.b])
+				}
+				gotA, gotB = append(gotA, a[o.a]), append(gotB, b[o.b])
+				equal++
+			case opDelete:
+				gotA = append(gotA, a[o.a])
+			case opInsert:
+				gotB = append(gotB, b[o.b])
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("%q -> %q: script rebuilds %q -> %q", a, b, gotA, gotB)
+		}
+		if want := lcs(a, b); equal != want {
+			t.Fatalf("%q -> %q: kept %d lines, want %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestUnifiedLargeRevisions(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < 20000; i++ {
+		from.WriteString("old " + strconv.Itoa(i) + "\n")
+		to.WriteString("new " + strconv.Itoa(i) + "\n")
+	}
+	got := Unified("old", "new", from.String(), to.String())
+	if !strings.HasPrefix(got, "--- old\n+++ new\n@@ -1,20000 +1,20000 @@\n-old 0\n") {
+		t.Errorf("got %.60q, want a single hunk replacing everything", got)
+	}
+}
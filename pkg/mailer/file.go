@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type FileMailer struct {
+	from string
+	dir  string
+	seq  uint64
+}
+
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	seq := atomic.AddUint64(&m.seq, 1)
+	name := fmt.Sprintf("%d-%d-%s.eml", time.Now().UnixNano(), seq, sanitizeFileName(msg.To))
+	return ioutil.WriteFile(filepath.Join(m.dir, name), render(m.from, msg), 0o644)
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
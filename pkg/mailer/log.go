@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"context"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+)
+
+type LogMailer struct {
+	from string
+	log  logger.Logger
+}
+
+func NewLogMailer(from string, log logger.Logger) *LogMailer {
+	return &LogMailer{
+		from: from,
+		log:  log,
+	}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.log.Infof("mail to %s from %s: %s\n%s", msg.To, m.from, msg.Subject, msg.Body)
+	return nil
+}
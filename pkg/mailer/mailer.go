@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/scraletteykt/my-blog/internal/config"
+	"github.com/scraletteykt/my-blog/pkg/logger"
+	"time"
+)
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func New(cfg config.MailConfig, log logger.Logger) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverLog, "":
+		return NewLogMailer(cfg.From, log), nil
+	case DriverFile:
+		return NewFileMailer(cfg.From, cfg.FileDir)
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+func render(from string, msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"github.com/scraletteykt/my-blog/internal/config"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	from string
+	cfg  config.SMTPConfig
+}
+
+func NewSMTPMailer(from string, cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{
+		from: from,
+		cfg:  cfg,
+	}
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	var a smtp.Auth
+	if m.cfg.Username != "" {
+		a = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	return smtp.SendMail(addr, a, sender.Address, []string{msg.To}, render(m.from, msg))
+}
@@ -0,0 +1,90 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func (s jwkSet) publicKeys() map[string]interface{} {
+	out := make(map[string]interface{}, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			if pub := k.rsa(); pub != nil {
+				out[k.Kid] = pub
+			}
+		case "EC":
+			if pub := k.ecdsa(); pub != nil {
+				out[k.Kid] = pub
+			}
+		}
+	}
+	return out
+}
+
+func (k jwk) rsa() *rsa.PublicKey {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil || !e.IsInt64() {
+		return nil
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}
+}
+
+func (k jwk) ecdsa() *ecdsa.PublicKey {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
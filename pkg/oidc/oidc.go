@@ -0,0 +1,271 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath   = "/.well-known/openid-configuration"
+	maxResponseSize = 1 << 20
+	clockSkew       = time.Minute
+)
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Provider talks to a single OpenID Connect issuer. Discovery and signing
+// keys are fetched lazily and cached, so an unavailable issuer does not
+// prevent the application from starting.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *discovery
+	keys map[string]interface{}
+}
+
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	return &Provider{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", Challenge(verifier))
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the verified ID token
+// claims.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	var tr tokenResponse
+	if err := p.do(req, &tr); err != nil {
+		if tr.Error != "" {
+			return nil, fmt.Errorf("oidc: token endpoint: %s: %s", tr.Error, tr.Description)
+		}
+		return nil, err
+	}
+	if tr.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	return p.VerifyIDToken(ctx, tr.IDToken, nonce)
+}
+
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	// Time claims are checked below, allowing for clock skew.
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}), jwt.WithoutClaimsValidation())
+	t, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil || !t.Valid {
+		return nil, ErrInvalidIDToken
+	}
+	now := time.Now()
+	switch {
+	case claims.Issuer != p.cfg.Issuer:
+		return nil, ErrInvalidIDToken
+	case !claims.VerifyAudience(p.cfg.ClientID, true):
+		return nil, ErrInvalidIDToken
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID:
+		return nil, ErrInvalidIDToken
+	case claims.ExpiresAt == nil || now.Add(-clockSkew).After(claims.ExpiresAt.Time):
+		return nil, ErrInvalidIDToken
+	case claims.NotBefore != nil && now.Add(clockSkew).Before(claims.NotBefore.Time):
+		return nil, ErrInvalidIDToken
+	case claims.Subject == "":
+		return nil, ErrInvalidIDToken
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, ErrInvalidIDToken
+	}
+	return claims, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta discovery
+	if err := p.do(req, &meta); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: expected %q, got %q", p.cfg.Issuer, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc: incomplete discovery document")
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	k, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+	// The issuer may have rotated its keys since we last looked.
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := p.fetchKeys(ctx, meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, nil
+		}
+	}
+	return nil, ErrInvalidIDToken
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwkSet
+	if err := p.do(req, &set); err != nil {
+		return nil, err
+	}
+	return set.publicKeys(), nil
+}
+
+func (p *Provider) do(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s %s: unexpected status %d", req.Method, req.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// NewRandom returns a URL-safe random string for state, nonce and PKCE
+// verifier values.
+func NewRandom() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,146 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/scraletteykt/my-blog/pkg/oidc/oidctest"
+)
+
+const (
+	testClientID = "my-blog"
+	testNonce    = "nonce"
+	testVerifier = "verifier"
+)
+
+func newTestProvider(issuer *oidctest.Issuer) *Provider {
+	return NewProvider(Config{
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"openid"},
+	}, issuer.Client())
+}
+
+func TestExchange(t *testing.T) {
+	issuer := oidctest.NewIssuer(testClientID)
+	defer issuer.Close()
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		claims  func(c jwt.MapClaims)
+		sign    func(c jwt.MapClaims) string
+		wantErr bool
+	}{
+		{name: "good login"},
+		{name: "wrong issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, wantErr: true},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, wantErr: true},
+		{name: "wrong nonce", claims: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, wantErr: true},
+		{name: "no subject", claims: func(c jwt.MapClaims) { c["sub"] = "" }, wantErr: true},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() }, wantErr: true},
+		{name: "expired within clock skew", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-clockSkew / 2).Unix() }},
+		{name: "not yet valid", claims: func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(2 * clockSkew).Unix() }, wantErr: true},
+		{name: "no expiry", claims: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{
+			name:    "several audiences without azp",
+			claims:  func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "someone-else"} },
+			wantErr: true,
+		},
+		{
+			name: "unknown key id",
+			sign: func(c jwt.MapClaims) string {
+				return issuer.SignWithKey(c, "unknown", other)
+			},
+			wantErr: true,
+		},
+		{
+			name: "unpublished key under a known id",
+			sign: func(c jwt.MapClaims) string {
+				return issuer.SignWithKey(c, "key-1", other)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := issuer.Claims("alice", testNonce)
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			raw := ""
+			if tt.sign != nil {
+				raw = tt.sign(claims)
+			} else {
+				raw = issuer.Sign(claims)
+			}
+			got, err := newTestProvider(issuer).Exchange(context.Background(), issuer.Code(raw), testVerifier, testNonce)
+			if tt.wantErr {
+				if err != ErrInvalidIDToken {
+					t.Errorf("got error %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Subject != "alice" || got.Issuer != issuer.URL {
+				t.Errorf("got subject %q issuer %q", got.Subject, got.Issuer)
+			}
+		})
+	}
+}
+
+func TestExchangeUnknownCode(t *testing.T) {
+	issuer := oidctest.NewIssuer(testClientID)
+	defer issuer.Close()
+	_, err := newTestProvider(issuer).Exchange(context.Background(), "bogus", testVerifier, testNonce)
+	if err == nil || err == ErrInvalidIDToken {
+		t.Errorf("got error %v, want the token endpoint's error", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	issuer := oidctest.NewIssuer(testClientID)
+	defer issuer.Close()
+	p := newTestProvider(issuer)
+	ctx := context.Background()
+
+	old := issuer.Sign(issuer.Claims("alice", testNonce))
+	if _, err := p.VerifyIDToken(ctx, old, testNonce); err != nil {
+		t.Fatalf("before rotation: %v", err)
+	}
+
+	// The provider has cached the first key; a token under the new one
+	// makes it fetch the keys again.
+	issuer.Rotate()
+	rotated := issuer.Sign(issuer.Claims("alice", testNonce))
+	if _, err := p.VerifyIDToken(ctx, rotated, testNonce); err != nil {
+		t.Fatalf("after rotation: %v", err)
+	}
+	// The first key is no longer published.
+	if _, err := p.VerifyIDToken(ctx, old, testNonce); err != ErrInvalidIDToken {
+		t.Errorf("retired key: got error %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	issuer := oidctest.NewIssuer(testClientID)
+	defer issuer.Close()
+	got, err := newTestProvider(issuer).AuthCodeURL(context.Background(), "state", testNonce, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := issuer.URL + "/authorize?client_id=my-blog&code_challenge=" + Challenge(testVerifier) +
+		"&code_challenge_method=S256&nonce=nonce&redirect_uri=http%3A%2F%2Flocalhost%2Fcallback&response_type=code&scope=openid&state=state"
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
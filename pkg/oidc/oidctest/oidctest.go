@@ -0,0 +1,156 @@
+// Package oidctest provides an OpenID Connect issuer for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Issuer serves discovery, a JWKS with its current signing key and a token
+// endpoint that redeems the codes handed out by Code.
+type Issuer struct {
+	*httptest.Server
+	ClientID string
+
+	mu    sync.Mutex
+	kid   string
+	keys  map[string]*rsa.PrivateKey
+	codes map[string]string
+	n     int
+}
+
+// NewIssuer starts an issuer for the client with one signing key. Close it
+// when done.
+func NewIssuer(clientID string) *Issuer {
+	i := &Issuer{
+		ClientID: clientID,
+		keys:     make(map[string]*rsa.PrivateKey),
+		codes:    make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.discovery)
+	mux.HandleFunc("/keys", i.jwks)
+	mux.HandleFunc("/token", i.token)
+	i.Server = httptest.NewServer(mux)
+	i.Rotate()
+	return i
+}
+
+// Rotate replaces the signing key with a new one, which is the only one the
+// JWKS lists from then on, and returns its key id.
+func (i *Issuer) Rotate() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.n++
+	i.kid = fmt.Sprintf("key-%d", i.n)
+	i.keys = map[string]*rsa.PrivateKey{i.kid: key}
+	return i.kid
+}
+
+// Claims returns valid ID token claims for the subject, for tests to adjust
+// before signing.
+func (i *Issuer) Claims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   i.URL,
+		"aud":   i.ClientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+}
+
+// Sign signs the claims with RS256 and the current key.
+func (i *Issuer) Sign(claims jwt.Claims) string {
+	i.mu.Lock()
+	kid, key := i.kid, i.keys[i.kid]
+	i.mu.Unlock()
+	return i.SignWithKey(claims, kid, key)
+}
+
+// SignWithKey signs the claims with RS256 and any key, published or not.
+func (i *Issuer) SignWithKey(claims jwt.Claims, kid string, key *rsa.PrivateKey) string {
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = kid
+	raw, err := t.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+// Code returns an authorization code the token endpoint exchanges for the
+// ID token.
+func (i *Issuer) Code(idToken string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(i.codes)+1)
+	i.codes[code] = idToken
+	return code
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/keys",
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	keys := make([]map[string]string, 0, len(i.keys))
+	for kid, key := range i.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	i.mu.Lock()
+	idToken, ok := i.codes[r.FormValue("code")]
+	delete(i.codes, r.FormValue("code"))
+	i.mu.Unlock()
+	if !ok || r.FormValue("code_verifier") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
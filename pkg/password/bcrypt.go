@@ -0,0 +1,49 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// bcryptMaxLength is the number of bytes bcrypt looks at; anything longer
+// would be silently truncated.
+const bcryptMaxLength = 72
+
+var ErrPasswordTooLong = errors.New("password is too long for bcrypt")
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxLength {
+		return "", ErrPasswordTooLong
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (b Bcrypt) Compare(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.Cost
+}
+
+func (b Bcrypt) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
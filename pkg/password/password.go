@@ -0,0 +1,62 @@
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidHash      = errors.New("invalid password hash")
+)
+
+// Algorithm hashes passwords into self-describing PHC-style strings.
+type Algorithm interface {
+	Name() string
+	Hash(password string) (string, error)
+	Compare(encoded, password string) (bool, error)
+	// Outdated reports whether encoded was made with weaker parameters
+	// than the algorithm is configured with now.
+	Outdated(encoded string) bool
+	// Handles reports whether encoded was produced by this algorithm.
+	Handles(encoded string) bool
+}
+
+// Hasher hashes new passwords with the preferred algorithm and verifies
+// hashes made by any of the known ones.
+type Hasher struct {
+	preferred  Algorithm
+	algorithms []Algorithm
+}
+
+func NewHasher(preferred Algorithm, others ...Algorithm) *Hasher {
+	return &Hasher{
+		preferred:  preferred,
+		algorithms: append([]Algorithm{preferred}, others...),
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Verify reports whether password matches encoded and, if so, whether the
+// hash should be replaced by one made with the current settings.
+func (h *Hasher) Verify(encoded, password string) (match, rehash bool, err error) {
+	for _, a := range h.algorithms {
+		if !a.Handles(encoded) {
+			continue
+		}
+		match, err = a.Compare(encoded, password)
+		if err != nil || !match {
+			return false, false, err
+		}
+		return true, a != h.preferred || a.Outdated(encoded), nil
+	}
+	// Accounts without a usable password (external sign in, deleted users)
+	// store a marker starting with "!".
+	if strings.HasPrefix(encoded, "!") {
+		return false, false, nil
+	}
+	return false, false, ErrUnknownAlgorithm
+}
@@ -0,0 +1,67 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort         = errors.New("password is too short")
+	ErrTooLong          = errors.New("password is too long")
+	ErrCommon           = errors.New("password is too common, choose another one")
+	ErrContainsUsername = errors.New("password must not contain the username")
+)
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+	blocklist map[string]struct{}
+}
+
+func NewPolicy(minLength, maxLength int) *Policy {
+	return &Policy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		blocklist: make(map[string]struct{}),
+	}
+}
+
+// LoadBlocklist reads breached or common passwords, one per line. Lines
+// starting with # are ignored and matching is case-insensitive.
+func (p *Policy) LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return s.Err()
+}
+
+func (p *Policy) Check(password, username string) error {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return ErrTooShort
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return ErrTooLong
+	}
+	if _, ok := p.blocklist[strings.ToLower(password)]; ok {
+		return ErrCommon
+	}
+	if len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return ErrContainsUsername
+	}
+	return nil
+}
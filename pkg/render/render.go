@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/scraletteykt/my-blog/pkg/sanitize"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+)
+
+// Version identifies the output of the current pipeline. Bump it whenever
+// rendering or the stats derived from it change so cached output made by an
+// older pipeline is redone.
+const Version = 3
+
+var ErrInvalidFormat = errors.New("content format must be one of markdown, html or plain")
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+type Renderer struct {
+	markdown  goldmark.Markdown
+	sanitizer *sanitize.Sanitizer
+}
+
+func New(sanitizer *sanitize.Sanitizer) *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			highlighting.NewHighlighting(
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	return &Renderer{
+		markdown:  md,
+		sanitizer: sanitizer,
+	}
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatMarkdown, FormatHTML, FormatPlain:
+		return true
+	default:
+		return false
+	}
+}
+
+// Render turns source in the given format into sanitized HTML.
+func (r *Renderer) Render(format, source string) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := r.markdown.Convert([]byte(source), &buf); err != nil {
+			return "", err
+		}
+		return r.sanitizer.HTML(buf.String()), nil
+	case FormatHTML:
+		return r.sanitizer.HTML(source), nil
+	case FormatPlain:
+		return plainToHTML(source), nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+func plainToHTML(source string) string {
+	source = strings.TrimSpace(strings.ReplaceAll(source, "\r\n", "\n"))
+	if source == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, p := range blankLines.Split(source, -1) {
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(strings.TrimSpace(p)), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
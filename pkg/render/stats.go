@@ -0,0 +1,163 @@
+package render
+
+import (
+	"golang.org/x/net/html"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type StatsConfig struct {
+	WordsPerMinute     int
+	CJKCharsPerMinute  int
+	CodeWordsPerMinute int
+	// ImageSeconds is the time spent on the first image. Each following
+	// image takes a second less, down to minImageSeconds.
+	ImageSeconds  int
+	ExcerptLength int
+}
+
+const minImageSeconds = 3
+
+type Stats struct {
+	WordCount   int
+	ReadingTime int
+	Excerpt     string
+}
+
+type counter struct {
+	words int
+	cjk   int
+}
+
+// Analyze computes word count, reading time in minutes and a plain-text
+// excerpt from rendered HTML. CJK characters are counted one per word since
+// those scripts do not separate words with spaces. Code blocks are read at
+// their own pace and left out of the word count and the excerpt.
+func Analyze(contentHTML string, cfg StatsConfig) Stats {
+	var (
+		prose, code counter
+		images      int
+		preDepth    int
+		skipDepth   int
+		excerpt     strings.Builder
+	)
+	z := html.NewTokenizer(strings.NewReader(contentHTML))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		name, _ := z.TagName()
+		tag := string(name)
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch {
+			case tag == "img":
+				images++
+			case tag == "pre" && tt == html.StartTagToken:
+				preDepth++
+			case isHeading(tag) && tt == html.StartTagToken:
+				skipDepth++
+			}
+			excerpt.WriteByte(' ')
+		case html.EndTagToken:
+			switch {
+			case tag == "pre" && preDepth > 0:
+				preDepth--
+			case isHeading(tag) && skipDepth > 0:
+				skipDepth--
+			}
+			excerpt.WriteByte(' ')
+		case html.TextToken:
+			text := string(z.Text())
+			if preDepth > 0 {
+				code.count(text)
+				continue
+			}
+			prose.count(text)
+			if skipDepth == 0 {
+				excerpt.WriteString(text)
+			}
+		}
+	}
+
+	seconds := minutesToSeconds(prose.words, cfg.WordsPerMinute) +
+		minutesToSeconds(prose.cjk, cfg.CJKCharsPerMinute) +
+		minutesToSeconds(code.words+code.cjk, cfg.CodeWordsPerMinute) +
+		imageSeconds(images, cfg.ImageSeconds)
+	readingTime := int(math.Ceil(seconds / 60))
+	if readingTime == 0 && (prose.words+prose.cjk+code.words+code.cjk+images) > 0 {
+		readingTime = 1
+	}
+
+	return Stats{
+		WordCount:   prose.words + prose.cjk,
+		ReadingTime: readingTime,
+		Excerpt:     truncate(strings.Join(strings.Fields(excerpt.String()), " "), cfg.ExcerptLength),
+	}
+}
+
+func (c *counter) count(text string) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			c.cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				c.words++
+			}
+			inWord = true
+		case unicode.IsSpace(r):
+			inWord = false
+		}
+	}
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isHeading(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+func minutesToSeconds(n, perMinute int) float64 {
+	if perMinute <= 0 {
+		return 0
+	}
+	return float64(n) * 60 / float64(perMinute)
+}
+
+func imageSeconds(images, first int) float64 {
+	var total int
+	for i := 0; i < images; i++ {
+		s := first - i
+		if s < minImageSeconds {
+			s = minImageSeconds
+		}
+		total += s
+	}
+	return float64(total)
+}
+
+// truncate shortens s to at most limit runes, preferring to cut at a space.
+func truncate(s string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)[:limit]
+	cut := len(runes)
+	for i := len(runes) - 1; i > len(runes)/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
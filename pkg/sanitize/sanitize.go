@@ -0,0 +1,58 @@
+package sanitize
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"regexp"
+)
+
+var (
+	classPattern = regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)
+	idPattern    = regexp.MustCompile(`^[a-zA-Z0-9_\-:]+$`)
+)
+
+// Config extends the built-in allowlist. Attributes maps an attribute name
+// to the elements it is allowed on.
+type Config struct {
+	URLSchemes []string
+	Elements   []string
+	Attributes map[string][]string
+}
+
+type Sanitizer struct {
+	html *bluemonday.Policy
+	text *bluemonday.Policy
+}
+
+// New builds an allowlist policy on top of bluemonday's user generated
+// content policy. Besides the configured additions it keeps what the
+// markdown renderer produces: highlighting classes, heading anchors and
+// footnote references.
+func New(cfg Config) *Sanitizer {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes(cfg.URLSchemes...)
+	p.RequireParseableURLs(true)
+	p.AllowAttrs("class").Matching(classPattern).OnElements("a", "code", "div", "li", "ol", "pre", "span", "sup", "hr")
+	p.AllowAttrs("id").Matching(idPattern).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("role").Matching(classPattern).OnElements("a", "div", "hr", "li", "ol")
+	if len(cfg.Elements) > 0 {
+		p.AllowElements(cfg.Elements...)
+	}
+	for attr, elements := range cfg.Attributes {
+		p.AllowAttrs(attr).OnElements(elements...)
+	}
+
+	return &Sanitizer{
+		html: p,
+		text: bluemonday.StrictPolicy(),
+	}
+}
+
+// HTML removes every element, attribute and URL that is not allowed.
+func (s *Sanitizer) HTML(src string) string {
+	return s.html.Sanitize(src)
+}
+
+// Text strips all markup, for fields that are plain text.
+func (s *Sanitizer) Text(src string) string {
+	return s.text.Sanitize(src)
+}
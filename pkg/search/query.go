@@ -0,0 +1,133 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const maxTerms = 32
+
+var ErrEmptyQuery = errors.New("search query has no words to look for")
+
+type term struct {
+	words  []string
+	prefix bool
+	negate bool
+}
+
+// ParseQuery turns a search box query into PostgreSQL tsquery syntax.
+// Words are required, "quoted phrases" must appear in order, word* matches
+// any word starting with word, -word excludes posts containing it, and OR
+// between two terms accepts either of them.
+func ParseQuery(q string) (string, error) {
+	var (
+		groups   [][]term
+		orNext   bool
+		positive bool
+	)
+	for _, t := range tokenize(q) {
+		if t == nil {
+			orNext = len(groups) > 0
+			continue
+		}
+		if len(groups) >= maxTerms {
+			break
+		}
+		if !t.negate {
+			positive = true
+		}
+		if orNext {
+			groups[len(groups)-1] = append(groups[len(groups)-1], *t)
+			orNext = false
+			continue
+		}
+		groups = append(groups, []term{*t})
+	}
+	if !positive {
+		return "", ErrEmptyQuery
+	}
+
+	parts := make([]string, 0, len(groups))
+	for _, g := range groups {
+		alts := make([]string, 0, len(g))
+		for _, t := range g {
+			alts = append(alts, t.String())
+		}
+		if len(alts) == 1 {
+			parts = append(parts, alts[0])
+		} else {
+			parts = append(parts, "("+strings.Join(alts, " | ")+")")
+		}
+	}
+	return strings.Join(parts, " & "), nil
+}
+
+// tokenize splits q into terms. A nil term stands for the OR operator.
+func tokenize(q string) []*term {
+	var out []*term
+	rest := strings.TrimSpace(q)
+	for rest != "" {
+		negate := false
+		if rest[0] == '-' {
+			negate = true
+			rest = rest[1:]
+		}
+		var raw string
+		phrase := false
+		if strings.HasPrefix(rest, `"`) {
+			phrase = true
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				raw, rest = rest[1:], ""
+			} else {
+				raw, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			raw, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		if !phrase && !negate && raw == "OR" {
+			out = append(out, nil)
+			continue
+		}
+		t := &term{words: words(raw), negate: negate}
+		if len(t.words) == 0 {
+			continue
+		}
+		t.prefix = !phrase && len(t.words) == 1 && strings.HasSuffix(raw, "*")
+		out = append(out, t)
+	}
+	return out
+}
+
+// words splits s into runs of letters and digits, dropping everything that
+// could be read as tsquery syntax.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (t term) String() string {
+	quoted := make([]string, 0, len(t.words))
+	for _, w := range t.words {
+		quoted = append(quoted, "'"+w+"'")
+	}
+	s := strings.Join(quoted, " <-> ")
+	if t.prefix {
+		s += ":*"
+	}
+	if t.negate {
+		if len(quoted) > 1 {
+			return "!(" + s + ")"
+		}
+		return "!" + s
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package sign
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// KeySource describes where a KeyRing takes its keys from. Keys holds
+// secrets known at startup (environment, config); File, when set, is read on
+// every Reload and its keys and active ID take precedence.
+type KeySource struct {
+	Active string
+	Keys   map[string]string
+	File   string
+}
+
+type keyFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// KeyRing holds the signing keys by ID. The active key signs new values;
+// the rest only verify values issued before a rotation, until they are
+// removed from the source.
+type KeyRing struct {
+	src KeySource
+
+	mu     sync.RWMutex
+	active string
+	keys   map[string][]byte
+}
+
+func LoadKeyRing(src KeySource) (*KeyRing, error) {
+	r := &KeyRing{src: src}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *KeyRing) Reload() error {
+	active := r.src.Active
+	keys := make(map[string][]byte)
+	for id, secret := range r.src.Keys {
+		if secret != "" {
+			keys[id] = []byte(secret)
+		}
+	}
+	if r.src.File != "" {
+		data, err := ioutil.ReadFile(r.src.File)
+		if err != nil {
+			return err
+		}
+		var f keyFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("key file %s: %w", r.src.File, err)
+		}
+		for id, secret := range f.Keys {
+			keys[id] = []byte(secret)
+		}
+		if f.Active != "" {
+			active = f.Active
+		}
+	}
+	if active == "" && len(keys) == 1 {
+		for id := range keys {
+			active = id
+		}
+	}
+	for id, secret := range keys {
+		if id == "" || strings.ContainsAny(id, ".:") {
+			return fmt.Errorf("invalid key id %q", id)
+		}
+		if len(secret) == 0 {
+			return fmt.Errorf("key %q is empty", id)
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return errors.New("active signing key is not in the key ring")
+	}
+
+	r.mu.Lock()
+	r.active, r.keys = active, keys
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *KeyRing) Active() (string, []byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active, r.keys[r.active]
+}
+
+func (r *KeyRing) Key(id string) ([]byte, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	return key, ok
+}
+
+func (r *KeyRing) all() [][]byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([][]byte, 0, len(r.keys))
+	for _, key := range r.keys {
+		out = append(out, key)
+	}
+	return out
+}
@@ -4,32 +4,53 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"strings"
 )
 
+const keyIDSep = "."
+
 type Signer struct {
-	Key string
+	keys *KeyRing
 }
 
-func NewSigner(key string) *Signer {
+func NewSigner(keys *KeyRing) *Signer {
 	return &Signer{
-		Key: key,
+		keys: keys,
 	}
 }
 
-func (e *Signer) Sign(src string) []byte {
-	h := hmac.New(sha256.New, []byte(e.Key))
-	h.Write([]byte(src))
-	return h.Sum(nil)
-}
-
-func (e *Signer) Verify(sign []byte, value string) bool {
-	return hmac.Equal(e.Sign(value), sign)
+// Sign returns the signature of src prefixed with the ID of the key that
+// made it.
+func (e *Signer) Sign(src string) string {
+	id, key := e.keys.Active()
+	return id + keyIDSep + base64.StdEncoding.EncodeToString(mac(key, src))
 }
 
-func (e *Signer) EncodeBase64(data []byte) string {
-	return base64.StdEncoding.EncodeToString(data)
+// Verify checks a signature made by any key still in the ring. Signatures
+// without a key ID predate the key ring and are checked against every key.
+func (e *Signer) Verify(signature, value string) bool {
+	id, encoded := "", signature
+	if i := strings.Index(signature, keyIDSep); i >= 0 {
+		id, encoded = signature[:i], signature[i+len(keyIDSep):]
+	}
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	if id == "" {
+		for _, key := range e.keys.all() {
+			if hmac.Equal(mac(key, value), sum) {
+				return true
+			}
+		}
+		return false
+	}
+	key, ok := e.keys.Key(id)
+	return ok && hmac.Equal(mac(key, value), sum)
 }
 
-func (e *Signer) DecodeBase64(sEnc string) ([]byte, error) {
-	return base64.StdEncoding.DecodeString(sEnc)
+func mac(key []byte, src string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(src))
+	return h.Sum(nil)
 }
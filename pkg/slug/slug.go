@@ -0,0 +1,112 @@
+package slug
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds generated slugs, leaving room for a uniqueness suffix.
+const MaxLength = 80
+
+// transliterations covers letters that do not decompose into an ASCII base
+// letter plus combining marks.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'ł': "l", 'đ': "d", 'ð': "d",
+	'þ': "th", 'ı': "i", 'ŋ': "ng", 'ħ': "h",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u",
+
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make turns s into a lowercase, hyphen separated slug. Latin, Cyrillic and
+// Greek letters are transliterated to ASCII; letters of other scripts are
+// kept as they are.
+func Make(s string) string {
+	return truncate(slugify(s), MaxLength)
+}
+
+// IsValid reports whether s is already in the form Make produces.
+func IsValid(s string) bool {
+	return s != "" && slugify(s) == s
+}
+
+// WithSuffix appends a numeric suffix, used to make a slug unique.
+func WithSuffix(s string, n int) string {
+	return s + "-" + strconv.Itoa(n)
+}
+
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFC.String(strings.ToLower(s)) {
+		t, ok := transliterate(r)
+		if !ok {
+			dash = true
+			continue
+		}
+		if t == "" {
+			continue
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(t)
+		dash = false
+	}
+	return b.String()
+}
+
+// transliterate maps a letter or digit to its ASCII spelling when there is
+// one. It reports false for runes that separate words.
+func transliterate(r rune) (string, bool) {
+	if r < utf8.RuneSelf {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			return string(r), true
+		}
+		return "", false
+	}
+	if t, ok := transliterations[r]; ok {
+		return t, true
+	}
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return "", unicode.Is(unicode.Mn, r)
+	}
+	// Accented letters decompose into a base letter and combining marks.
+	d := []rune(norm.NFKD.String(string(r)))
+	if d[0] < utf8.RuneSelf {
+		var b strings.Builder
+		for _, c := range d {
+			if c < utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+				b.WriteRune(unicode.ToLower(c))
+			}
+		}
+		return b.String(), true
+	}
+	if t, ok := transliterations[unicode.ToLower(d[0])]; ok {
+		return t, true
+	}
+	return string(r), true
+}
+
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	s = string([]rune(s)[:limit])
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, "-")
+}
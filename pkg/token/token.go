@@ -0,0 +1,104 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/scraletteykt/my-blog/pkg/sign"
+	"strconv"
+	"time"
+)
+
+const refreshTokenBytes = 32
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	UserID    int    `json:"uid"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+type Manager struct {
+	keys *sign.KeyRing
+	ttl  time.Duration
+}
+
+func NewManager(keys *sign.KeyRing, ttl time.Duration) *Manager {
+	return &Manager{
+		keys: keys,
+		ttl:  ttl,
+	}
+}
+
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *Manager) NewAccessToken(userID int, username, role string, sessionID int) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+	claims := Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	kid, key := m.keys.Active()
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t.Header["kid"] = kid
+	signed, err := t.SignedString(key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func (m *Manager) Parse(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+	t, err := jwt.ParseWithClaims(accessToken, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			_, key := m.keys.Active()
+			return key, nil
+		}
+		key, ok := m.keys.Key(kid)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return key, nil
+	})
+	if err != nil || !t.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// NewOpaque returns a random URL-safe token that carries no data and is only
+// meaningful to the server which stored its hash.
+func NewOpaque() (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}